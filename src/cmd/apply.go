@@ -0,0 +1,410 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/kettleofketchup/kettle/src/cmd/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	stateFile string
+	applyJobs int
+)
+
+// versionAny and versionLatest are the special tool versions in a state
+// file; anything else is a version prefix.
+const (
+	versionAny    = ""
+	versionLatest = "latest"
+)
+
+// planned is a change with what applies it.
+type planned struct {
+	state.Change
+	// tool and want are set for tools, which are applied together.
+	tool     *registry.Tool
+	want     string
+	resource state.Resource
+}
+
+// loadState reads the --file state file, or the default one.
+func loadState() (*state.Document, string, error) {
+	path := stateFile
+	if path == "" {
+		var err error
+		if path, err = state.DefaultPath(); err != nil {
+			return nil, "", err
+		}
+	}
+	doc, err := state.Load(path)
+	return doc, path, err
+}
+
+// planTools compares the tools of doc, and of its sets, with the machine.
+func planTools(ctx context.Context, doc *state.Document) ([]planned, error) {
+	wants := map[string]string{}
+	for _, name := range doc.Sets {
+		set, err := lookupSet(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, tool := range set.Tools {
+			wants[tool] = versionAny
+		}
+	}
+	for name, want := range doc.Tools {
+		wants[name] = want
+	}
+	names := make([]string, 0, len(wants))
+	for name := range wants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []planned
+	for _, name := range names {
+		tool, err := lookupTool(name)
+		if err != nil {
+			return nil, err
+		}
+		want := wants[name]
+		p := planned{Change: state.Change{Resource: "tool " + name, Action: state.ActionNone, Desired: want}, tool: &tool, want: want}
+		if want == versionAny {
+			p.Desired = "any version"
+		}
+		st := registry.Detect(ctx, tool)
+		p.Current = st.Version
+		switch {
+		case !st.Installed:
+			p.Action = state.ActionCreate
+			p.Current = ""
+			if want == versionLatest && tool.Latest != nil {
+				if rel, err := tool.Latest(ctx); err == nil {
+					p.Desired = rel.Version
+				}
+			}
+		case want == versionAny:
+		case want == versionLatest:
+			if tool.Latest == nil {
+				p.Action, p.Error = state.ActionUnknown, "kettle cannot tell the latest version of "+name
+				break
+			}
+			rel, err := tool.Latest(ctx)
+			if err != nil {
+				p.Action, p.Error = state.ActionUnknown, err.Error()
+				break
+			}
+			p.Desired = rel.Version
+			if helpers.CompareVersions(st.Version, rel.Version) < 0 {
+				p.Action = state.ActionUpdate
+			}
+		case !helpers.VersionMatches(st.Version, want):
+			p.Action = state.ActionUpdate
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// planState compares the machine with doc: tools first, then the other
+// resources in the order they are applied.
+func planState(ctx context.Context, doc *state.Document) ([]planned, error) {
+	out, err := planTools(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := doc.Resources()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resources {
+		c, err := r.Plan(ctx)
+		if err != nil {
+			c.Action, c.Error = state.ActionUnknown, err.Error()
+		}
+		out = append(out, planned{Change: c, resource: r})
+	}
+	return out, nil
+}
+
+// pendingChanges returns the changes in plan that apply would make.
+func pendingChanges(plan []planned) []planned {
+	var out []planned
+	for _, p := range plan {
+		if p.Action == state.ActionCreate || p.Action == state.ActionUpdate {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// printPlan shows the differences in plan and their diffs. It returns
+// false when there are none.
+func printPlan(plan []planned) bool {
+	var rows [][]string
+	var diffs []planned
+	for _, p := range plan {
+		if p.Action == state.ActionNone {
+			continue
+		}
+		current := p.Current
+		if p.Error != "" {
+			current = p.Error
+		}
+		rows = append(rows, []string{p.Resource, p.Action, current, p.Desired})
+		if len(p.Diff) > 0 {
+			diffs = append(diffs, p)
+		}
+	}
+	if len(rows) == 0 {
+		return false
+	}
+	fmt.Println(renderTable([]string{"Resource", "Action", "Current", "Desired"}, rows))
+	for _, p := range diffs {
+		helpers.PrintInfo(p.Resource)
+		helpers.PrintDiff(p.Diff)
+	}
+	return true
+}
+
+func changesOf(plan []planned) []state.Change {
+	out := make([]state.Change, len(plan))
+	for i, p := range plan {
+		out[i] = p.Change
+	}
+	return out
+}
+
+// applyTools installs missing tools along the dependency graph, installs
+// pinned versions, and upgrades tools that should be on the latest.
+func applyTools(ctx context.Context, changes []planned) error {
+	var errs []error
+	var missing, upgrade []string
+	for _, p := range changes {
+		switch {
+		case p.tool == nil:
+		case p.want != versionAny && p.want != versionLatest:
+			if p.tool.InstallVersion == nil {
+				errs = append(errs, fmt.Errorf("%s cannot install a specific version; use any or latest", p.tool.Name))
+				continue
+			}
+			err := p.tool.InstallVersion(ctx, p.want)
+			recordToolResult(*p.tool, err)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to install %s %s: %w", p.tool.Name, p.want, err))
+			}
+		case p.want == versionLatest && p.tool.InstallVersion != nil && p.Desired != versionLatest:
+			// Desired is the latest release, looked up by the plan.
+			err := installToolVersion(ctx, *p.tool, p.Desired)
+			recordToolResult(*p.tool, err)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to install %s %s: %w", p.tool.Name, p.Desired, err))
+			}
+		case p.Action == state.ActionCreate:
+			missing = append(missing, p.tool.Name)
+		default:
+			upgrade = append(upgrade, p.tool.Name)
+		}
+	}
+
+	var results []helpers.ToolResult
+	if len(missing) > 0 {
+		resolved, err := registry.Resolve(missing)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		todo, present, err := pendingTools(ctx, resolved, nil)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		installed, err := installGraph(ctx, todo, applyJobs)
+		results = append(append(results, present...), installed...)
+		errs = append(errs, err)
+	}
+	if len(upgrade) > 0 {
+		resolved, err := registry.Resolve(upgrade)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		var todo []registry.Tool
+		for _, tool := range resolved {
+			if slices.Contains(upgrade, tool.Name) {
+				todo = append(todo, tool)
+			}
+		}
+		helpers.SetReinstall(true)
+		upgraded, err := installGraph(ctx, todo, applyJobs)
+		helpers.SetReinstall(false)
+		results = append(results, upgraded...)
+		errs = append(errs, err)
+	}
+	printInstallSummary(results)
+	return errors.Join(errs...)
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show how the machine differs from the state file",
+	Long: `Compare the machine with the desired-state file (~/.config/kettle/state.yaml,
+or --file) and show what kettle apply would change, with a diff for files.
+Nothing is changed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		doc, path, err := loadState()
+		if err != nil {
+			return err
+		}
+		plan, err := planState(cmd.Context(), doc)
+		if err != nil {
+			return err
+		}
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(changesOf(plan))
+			return nil
+		}
+		if !printPlan(plan) {
+			helpers.PrintSuccess(fmt.Sprintf("The machine matches %s", path))
+			return nil
+		}
+		helpers.PrintInfo(fmt.Sprintf("%d change(s); run kettle apply to make them", len(pendingChanges(plan))))
+		return nil
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Converge the machine to the state file",
+	Long: `Make the machine match the desired-state file (~/.config/kettle/state.yaml,
+or --file): install tools and versions, write managed files, add shell
+profile lines and set GNOME settings and keybindings. Only what differs is
+changed, so running apply twice changes nothing the second time.
+
+A state file looks like:
+
+  tools:
+    go: "1.23"            # a version prefix, latest, or "" for any
+    starship: latest
+  sets: [terminal]
+  shell:
+    - export EDITOR=vim
+  files:
+    - path: ~/.config/ghostty/config
+      source: ghostty.conf  # relative to the state file, or use content:
+  gnome:
+    settings:
+      - {schema: org.gnome.desktop.interface, key: color-scheme, value: "'prefer-dark'"}
+    keybindings:
+      - {name: Terminal, binding: <Super>Return, command: ghostty}
+
+The hashes of the files written are recorded, so kettle drift can tell when
+one is edited by hand.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		doc, path, err := loadState()
+		if err != nil {
+			return err
+		}
+		plan, err := planState(ctx, doc)
+		if err != nil {
+			return err
+		}
+		changes := pendingChanges(plan)
+		if !helpers.IsStructuredOutput() {
+			printPlan(plan)
+		}
+
+		var errs []error
+		// failed holds the resources that could not be planned or applied,
+		// whose files are not recorded as managed.
+		failed := map[string]bool{}
+		for _, p := range plan {
+			if p.Action == state.ActionUnknown {
+				failed[p.Resource] = true
+				errs = append(errs, fmt.Errorf("%s: %s", p.Resource, p.Error))
+			}
+		}
+		if len(changes) > 0 {
+			if !helpers.PromptYesNo(fmt.Sprintf("Apply %d change(s)?", len(changes)), true) {
+				return helpers.ErrDeclined
+			}
+			errs = append(errs, applyTools(ctx, changes))
+			for _, p := range changes {
+				if p.resource == nil {
+					continue
+				}
+				if err := p.resource.Apply(ctx); err != nil {
+					failed[p.Resource] = true
+					helpers.PrintFail(fmt.Sprintf("%s: %v", p.Resource, err))
+					errs = append(errs, fmt.Errorf("%s: %w", p.Resource, err))
+				}
+			}
+		}
+		var managed []string
+		for _, path := range doc.ManagedFiles() {
+			if !failed[state.FileResource(path)] {
+				managed = append(managed, path)
+			}
+		}
+		if err := state.RecordManaged(managed); err != nil {
+			errs = append(errs, fmt.Errorf("failed to record managed files: %w", err))
+		}
+
+		helpers.SetReportData(changesOf(changes))
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			helpers.PrintSuccess(fmt.Sprintf("The machine already matches %s", path))
+		} else {
+			helpers.PrintSuccess(fmt.Sprintf("Applied %d change(s) from %s", len(changes), path))
+		}
+		return nil
+	},
+}
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Check whether files written by kettle apply were edited by hand",
+	Long: `Compare the files kettle apply manages with the hashes recorded when they
+were written. Exits with status 4 when one was edited or removed; run
+kettle plan to see the difference and kettle apply to restore it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		drift, err := state.CheckDrift()
+		if err != nil {
+			return err
+		}
+		helpers.SetReportData(drift)
+		if len(drift) == 0 {
+			helpers.PrintSuccess("No drift: managed files are as kettle apply left them")
+			return nil
+		}
+		if !helpers.IsStructuredOutput() {
+			rows := make([][]string, len(drift))
+			for i, d := range drift {
+				rows[i] = []string{d.Path, d.Status}
+			}
+			fmt.Println(renderTable([]string{"File", "Status"}, rows))
+		}
+		return fmt.Errorf("%w in %d managed file(s)", helpers.ErrDrift, len(drift))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(driftCmd)
+
+	for _, c := range []*cobra.Command{planCmd, applyCmd} {
+		c.Flags().StringVarP(&stateFile, "file", "f", "", "State file (default ~/.config/kettle/state.yaml)")
+	}
+	applyCmd.Flags().IntVarP(&applyJobs, "jobs", "j", 4, "Number of tools to install at once")
+}
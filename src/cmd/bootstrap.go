@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/kettleofketchup/kettle/src/cmd/sets"
+	"github.com/spf13/cobra"
+)
+
+// Bootstrap step results.
+const (
+	StepChanged   = "changed"
+	StepUnchanged = "unchanged"
+	StepSkipped   = "skipped"
+	StepFailed    = "failed"
+)
+
+// bootstrapPrerequisites are the commands installers rely on, installed
+// from system packages of the same name.
+var bootstrapPrerequisites = []string{"curl", "git", "tar"}
+
+var (
+	bootstrapSet  string
+	bootstrapJobs int
+)
+
+// BootstrapStep is one stage of kettle bootstrap.
+type BootstrapStep struct {
+	Step    string `json:"step" yaml:"step"`
+	Status  string `json:"status" yaml:"status"`
+	Changes int    `json:"changes" yaml:"changes"`
+	Detail  string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+// BootstrapReport is what kettle bootstrap did.
+type BootstrapReport struct {
+	Platform helpers.Platform `json:"platform" yaml:"platform"`
+	Steps    []BootstrapStep  `json:"steps" yaml:"steps"`
+	// NewShell lists the shell profile files that changed; they only take
+	// effect in a new shell.
+	NewShell []string `json:"new_shell,omitempty" yaml:"new_shell,omitempty"`
+}
+
+// step runs fn as the named stage, noting the side effects it applied and
+// any profile files it changed.
+func (r *BootstrapReport) step(name string, fn func() (string, error)) error {
+	before := len(helpers.Changes())
+	detail, err := fn()
+	changes := helpers.Changes()[before:]
+
+	s := BootstrapStep{Step: name, Status: StepUnchanged, Changes: len(changes), Detail: detail}
+	switch {
+	case err != nil:
+		s.Status = StepFailed
+		s.Detail = err.Error()
+	case len(changes) > 0:
+		s.Status = StepChanged
+	}
+	for _, c := range changes {
+		if c.Kind == "append" && !slices.Contains(r.NewShell, c.Target) {
+			r.NewShell = append(r.NewShell, c.Target)
+		}
+	}
+	r.Steps = append(r.Steps, s)
+	return err
+}
+
+func (r *BootstrapReport) skip(name, detail string) {
+	r.Steps = append(r.Steps, BootstrapStep{Step: name, Status: StepSkipped, Detail: detail})
+}
+
+func (r *BootstrapReport) print() {
+	rows := make([][]string, 0, len(r.Steps))
+	for _, s := range r.Steps {
+		rows = append(rows, []string{s.Step, s.Status, strconv.Itoa(s.Changes), s.Detail})
+	}
+	fmt.Println(renderTable([]string{"Step", "Result", "Changes", "Detail"}, rows))
+	if len(r.NewShell) > 0 {
+		helpers.PrintInfo(fmt.Sprintf("Open a new shell, or run `exec $SHELL`, to pick up changes to %s", strings.Join(r.NewShell, ", ")))
+	}
+}
+
+// installPrerequisites installs whichever of bootstrapPrerequisites are
+// missing with the system package manager.
+func installPrerequisites(ctx context.Context, platform helpers.Platform) (string, error) {
+	var missing []string
+	for _, name := range bootstrapPrerequisites {
+		if _, err := exec.LookPath(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return strings.Join(bootstrapPrerequisites, ", ") + " already present", nil
+	}
+	if err := helpers.InstallPackages(ctx, platform.PackageManager, missing...); err != nil {
+		return "", err
+	}
+	return "installed " + strings.Join(missing, ", "), nil
+}
+
+// applySet installs the missing tools of the named set.
+func applySet(ctx context.Context, name string) (string, error) {
+	set, err := lookupSet(name)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := registry.Resolve(set.Tools)
+	if err != nil {
+		return "", fmt.Errorf("set %s: %w", set.Name, err)
+	}
+	todo, present, err := pendingTools(ctx, resolved, nil)
+	if err != nil {
+		return "", err
+	}
+	results, err := installGraph(ctx, todo, bootstrapJobs)
+	printInstallSummary(append(present, results...))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d installed, %d already installed", len(results), len(present)), nil
+}
+
+var bootstrapCmd = &cobra.Command{
+	Use:   "bootstrap",
+	Short: "Set up a fresh machine in one go",
+	Long: `Set up a fresh machine: detect the OS, distribution and shell, install the
+prerequisites installers need (curl, git and tar) with the system package
+manager, install kettle itself, install the tools of a set with --set, and
+generate shell completions.
+
+Every step runs even if an earlier one failed. A final report shows what
+changed and which shell profiles need a new shell to take effect.`,
+	Example: `  kettle bootstrap --set go-dev
+  kettle bootstrap --set backend --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		report := &BootstrapReport{Platform: helpers.DetectPlatform(ctx)}
+		helpers.PrintInfo(fmt.Sprintf("Detected %s", report.Platform))
+		if bootstrapSet != "" {
+			// Fail before changing anything when the set does not exist.
+			if _, err := lookupSet(bootstrapSet); err != nil {
+				return err
+			}
+		}
+
+		var errs []error
+		errs = append(errs, report.step("prerequisites", func() (string, error) {
+			return installPrerequisites(ctx, report.Platform)
+		}))
+		errs = append(errs, report.step("kettle", func() (string, error) {
+			if err := installSelf(); err != nil {
+				return "", err
+			}
+			dir, err := helpers.GetInstallDir()
+			return "installed to " + dir, err
+		}))
+		if bootstrapSet != "" {
+			errs = append(errs, report.step("set "+bootstrapSet, func() (string, error) {
+				return applySet(ctx, bootstrapSet)
+			}))
+		} else {
+			report.skip("set", "no --set given")
+		}
+		if helpers.IsSystemInstall() {
+			report.skip("completions", "not generated for --system installs")
+		} else {
+			errs = append(errs, report.step("completions", func() (string, error) {
+				if err := installCompletions(); err != nil {
+					return "", err
+				}
+				return strings.Join(completionShells(), ", "), nil
+			}))
+		}
+
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(report)
+		} else {
+			report.print()
+		}
+		return errors.Join(errs...)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bootstrapCmd)
+
+	bootstrapCmd.Flags().StringVar(&bootstrapSet, "set", "", "Install the tools of this set")
+	bootstrapCmd.Flags().IntVarP(&bootstrapJobs, "jobs", "j", 4, "Number of tools to install at once")
+	_ = bootstrapCmd.RegisterFlagCompletionFunc("set", sets.Names)
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/internal/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// configOptional marks commands that must still run when the config file
+// is broken, so it can be fixed.
+const configOptional = "kettle.config-optional"
+
+// configPath returns the config file in use: --config, $KETTLE_CONFIG or the default.
+func configPath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	return config.DefaultPath()
+}
+
+// writeConfigKey sets key to value in the config file, keeping the rest of
+// the file as it is, and returns the file's path.
+func writeConfigKey(key, value string) (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read config: %w", err)
+	}
+	updated, err := config.SetInFile(data, key, value)
+	if err != nil {
+		return "", err
+	}
+	if err := helpers.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := helpers.WriteFile(path, updated, 0644); err != nil {
+		return "", fmt.Errorf("failed to write config: %w", err)
+	}
+	return path, nil
+}
+
+// loadConfig loads the config file, applies KETTLE_* overrides and makes
+// the result current. Flags are applied on top by the caller.
+func loadConfig(cmd *cobra.Command) (config.Config, error) {
+	cfg, err := readConfig()
+	if err != nil {
+		if _, ok := cmd.Annotations[configOptional]; !ok {
+			return config.Config{}, err
+		}
+		helpers.PrintError("Ignoring invalid config", err)
+		cfg = config.Default()
+	}
+	config.SetCurrent(cfg)
+
+	if cfg.Proxy != "" && os.Getenv("HTTPS_PROXY") == "" && os.Getenv("https_proxy") == "" {
+		_ = os.Setenv("HTTPS_PROXY", cfg.Proxy)
+		_ = os.Setenv("HTTP_PROXY", cfg.Proxy)
+	}
+	return cfg, nil
+}
+
+func readConfig() (config.Config, error) {
+	path, err := configPath()
+	if err != nil {
+		return config.Config{}, err
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return config.Config{}, err
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		return config.Config{}, err
+	}
+	return cfg, nil
+}
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change kettle's configuration",
+	Long: fmt.Sprintf(`Show and change kettle's configuration file.
+
+Values come from the config file, then KETTLE_* environment variables
+(for example KETTLE_INSTALL_PREFIX or KETTLE_TOOLS_DISABLED), then flags.
+
+Keys: %s`, strings.Join(config.Keys(), ", ")),
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a config key",
+	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return config.Keys(), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := config.Current().Get(args[0])
+		if err != nil {
+			return err
+		}
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(map[string]string{"key": args[0], "value": value})
+			return nil
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a config key in the config file (an empty value removes it)",
+	Long: `Set a config key in the config file. Lists such as shells or tools.disabled
+take comma separated values, and an empty value removes the key.`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return config.Keys(), cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Annotations: map[string]string{configOptional: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := writeConfigKey(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		helpers.PrintSuccess(fmt.Sprintf("Set %s in %s", args[0], path))
+		return nil
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:         "edit",
+	Short:       "Open the config file in $VISUAL or $EDITOR and validate it",
+	Annotations: map[string]string{configOptional: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configPath()
+		if err != nil {
+			return err
+		}
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+		}
+		editorCmd := helpers.Command{Args: append(strings.Fields(editor), path)}
+
+		if helpers.IsDryRun() {
+			_, err := helpers.Exec(cmd.Context(), editorCmd)
+			return err
+		}
+		if err := helpers.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create config directory: %w", err)
+		}
+
+		for {
+			if err := helpers.RunAttached(cmd.Context(), editorCmd); err != nil {
+				return fmt.Errorf("editor failed: %w", err)
+			}
+			_, err := config.Load(path)
+			if err == nil {
+				helpers.PrintSuccess(fmt.Sprintf("%s is valid", path))
+				return nil
+			}
+			helpers.PrintError("Config is invalid", err)
+			if !helpers.IsInteractive() || !helpers.PromptYesNo("Edit the config again?", true) {
+				return err
+			}
+		}
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long:  `Print the configuration in effect after applying the config file and KETTLE_* environment variables.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.Current()
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(cfg)
+			return nil
+		}
+		path, err := configPath()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s\n", path)
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(cfg); err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+		return enc.Close()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configShowCmd)
+}
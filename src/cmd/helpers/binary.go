@@ -0,0 +1,83 @@
+package helpers
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var elfArchs = map[elf.Machine]string{
+	elf.EM_X86_64:  "amd64",
+	elf.EM_AARCH64: "arm64",
+	elf.EM_386:     "386",
+	elf.EM_ARM:     "arm",
+	elf.EM_RISCV:   "riscv64",
+	elf.EM_S390:    "s390x",
+}
+
+var machoArchs = map[macho.Cpu]string{
+	macho.CpuAmd64: "amd64",
+	macho.CpuArm64: "arm64",
+	macho.Cpu386:   "386",
+}
+
+var peArchs = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_AMD64: "amd64",
+	pe.IMAGE_FILE_MACHINE_ARM64: "arm64",
+	pe.IMAGE_FILE_MACHINE_I386:  "386",
+}
+
+// BinaryPlatform reads the GOOS and GOARCH an executable was built for from
+// its ELF, Mach-O or PE header. A universal Mach-O binary has several
+// architectures.
+func BinaryPlatform(path string) (goos string, goarchs []string, err error) {
+	if f, ferr := elf.Open(path); ferr == nil {
+		defer IOClose(f, &err)
+		goos = "linux"
+		if f.OSABI == elf.ELFOSABI_FREEBSD {
+			goos = "freebsd"
+		}
+		arch := elfArchs[f.Machine]
+		if f.Machine == elf.EM_PPC64 {
+			arch = "ppc64"
+			if f.ByteOrder == binary.LittleEndian {
+				arch = "ppc64le"
+			}
+		}
+		return goos, []string{arch}, nil
+	}
+	if f, ferr := macho.Open(path); ferr == nil {
+		defer IOClose(f, &err)
+		return "darwin", []string{machoArchs[f.Cpu]}, nil
+	}
+	if f, ferr := macho.OpenFat(path); ferr == nil {
+		defer IOClose(f, &err)
+		for _, a := range f.Arches {
+			goarchs = append(goarchs, machoArchs[a.Cpu])
+		}
+		return "darwin", goarchs, nil
+	}
+	if f, ferr := pe.Open(path); ferr == nil {
+		defer IOClose(f, &err)
+		return "windows", []string{peArchs[f.Machine]}, nil
+	}
+	return "", nil, fmt.Errorf("%w: %s is not an ELF, Mach-O or PE executable", ErrVerification, path)
+}
+
+// CheckBinaryPlatform returns an error unless the executable at path runs
+// on goos and goarch.
+func CheckBinaryPlatform(path, goos, goarch string) error {
+	gotOS, gotArchs, err := BinaryPlatform(path)
+	if err != nil {
+		return err
+	}
+	if gotOS != goos || !slices.Contains(gotArchs, goarch) {
+		return fmt.Errorf("%w: %s is built for %s/%s, not %s/%s",
+			ErrVerification, path, gotOS, strings.Join(gotArchs, ","), goos, goarch)
+	}
+	return nil
+}
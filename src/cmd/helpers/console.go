@@ -2,18 +2,13 @@
 package helpers
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
-	"strings"
 	"sync"
 
-	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	log "github.com/charmbracelet/log"
-	"github.com/charmbracelet/x/term"
 )
 
 var (
@@ -45,95 +40,46 @@ var (
 			Foreground(lipgloss.Color("12"))
 )
 
-// RunCmd runs a bash command with TUI output and displays the results in panels.
-// Optional output parameter controls whether to print command output (default: true)
-func RunCmd(command string, output ...bool) error {
-	// Determine if we should print output (default: true)
-	shouldPrintOutput := true
-	if len(output) > 0 {
-		shouldPrintOutput = output[0]
-	}
-
-	// Start TUI if not already running
-	// Update status panel to show current command
-	if shouldPrintOutput {
-		PrintInfo(fmt.Sprintf("Running: %s", command))
-	}
-
-	var cmd *exec.Cmd
-	if strings.HasPrefix(command, "sudo") {
-		pw, err := getSudoPassword()
-		if err != nil {
-			e := fmt.Errorf("failed to get sudo password: %w", err)
-			PrintErrors(e)
-			return e
-		}
-		cmd = exec.Command("sudo", "-S", "bash", "-c", command)
-		cmd.Stdin = strings.NewReader(pw)
-	} else {
-		cmd = exec.Command("bash", "-c", command)
-	}
-
-	stdout, _ := cmd.StdoutPipe()
-	stderr, _ := cmd.StderrPipe()
-
-	err := cmd.Start()
-	if err != nil {
-		v := fmt.Errorf("failed to start command: %w", err)
-		if shouldPrintOutput {
-			PrintErrors(v)
-		}
-		return v
-	}
-
-	scanner := bufio.NewScanner(io.MultiReader(stdout, stderr))
-	go func() {
-		for scanner.Scan() {
-			if shouldPrintOutput {
-				PrintCmdOutput(scanner.Text())
-			}
-		}
-	}()
-
-	err = cmd.Wait()
-
-	if err != nil {
-		if shouldPrintOutput {
-			PrintError(fmt.Sprintf("Command failed: %s", command))
-		}
-		return err
+var (
+	consoleMu  sync.RWMutex
+	consoleOut io.Writer = os.Stdout
+	quiet      bool
+)
 
-	}
+// SetOutput sets where messages and command output are printed.
+// Reports and tables always go to stdout.
+func SetOutput(w io.Writer) {
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+	consoleOut = w
+}
 
-	if shouldPrintOutput {
-		PrintInfo(fmt.Sprintf("Completed: %s", command))
-	}
-	return nil
+// SetQuiet hides info messages and command output; successes and
+// errors are still printed.
+func SetQuiet(enabled bool) {
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+	quiet = enabled
 }
 
-var (
-	sudoPassword string
-	sudoOnce     sync.Once
-)
+// IsQuiet reports whether --quiet is in effect.
+func IsQuiet() bool {
+	consoleMu.RLock()
+	defer consoleMu.RUnlock()
+	return quiet
+}
 
-func getSudoPassword() (string, error) {
-	var err error
-	sudoOnce.Do(func() {
-		fmt.Print("Enter sudo password: ")
-		pw, e := term.ReadPassword(os.Stdin.Fd())
-		fmt.Println()
-		if e != nil {
-			err = e
-			return
-		}
-		sudoPassword = string(pw)
-	})
-	return sudoPassword, err
+func console() io.Writer {
+	consoleMu.RLock()
+	defer consoleMu.RUnlock()
+	return consoleOut
 }
 
 func PrintFail(msg string) {
-
-	fmt.Println(errorStyle.Render("✗ " + msg))
+	if recordEvent("error", msg) {
+		return
+	}
+	fmt.Fprintln(console(), errorStyle.Render("✗ "+msg))
 }
 
 func PrintErrors(err ...error) {
@@ -143,57 +89,47 @@ func PrintErrors(err ...error) {
 }
 
 func PrintError(msg string, err ...error) {
-	PrintFail(msg)
-
+	detail := msg
 	if len(err) > 0 && err[0] != nil {
 
-		msg = fmt.Sprintf("%s: %v", msg, err[0])
+		detail = fmt.Sprintf("%s: %v", msg, err[0])
 
 	}
-	log.Error(msg)
-	fmt.Println(errorPanelStyle.Render("✗  " + msg))
+	log.Error(detail)
+	if recordEvent("error", detail) {
+		return
+	}
+	PrintFail(msg)
+	fmt.Fprintln(console(), errorPanelStyle.Render("✗  "+detail))
 }
 
+// PrintSuccess reports a finished step. A dry run has done nothing yet,
+// so there it is only logged; the plan printed at the end says what would
+// change.
 func PrintSuccess(msg string) {
+	if IsDryRun() {
+		log.Debug("dry run: " + msg)
+		return
+	}
 	log.Info(msg)
-
-	fmt.Println(successStyle.Render("✓  " + msg))
+	if recordEvent("success", msg) {
+		return
+	}
+	fmt.Fprintln(console(), successStyle.Render("✓  "+msg))
 }
 
 func PrintInfo(msg string) {
 	log.Debug(msg)
-	fmt.Println(infoStyle.Render("ℹ  " + msg))
+	if recordEvent("info", msg) || IsQuiet() {
+		return
+	}
+	fmt.Fprintln(console(), infoStyle.Render("ℹ  "+msg))
 }
 
 func PrintCmdOutput(msg string) {
 	log.Debug(msg)
-	fmt.Println(cmdStyle.Render(" ➜  CmdOut: " + msg))
-}
-
-// PromptYesNo prompts the user with a yes/no question and returns true for yes, false for no
-func PromptYesNo(question string) bool {
-	var confirm bool
-
-	fmt.Println(question)
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewConfirm().
-				Title(question).
-				Description("Do you want to continue?").
-				Value(&confirm),
-		),
-	)
-
-	if err := form.Run(); err != nil {
-		PrintError("Error:", err)
-		return false
-	}
-
-	if confirm {
-		fmt.Println("Continuing...")
-	} else {
-		fmt.Println("Aborted.")
+	if recordEvent("output", msg) || IsQuiet() {
+		return
 	}
-	return confirm
-
+	fmt.Fprintln(console(), cmdStyle.Render(" ➜  CmdOut: "+msg))
 }
@@ -0,0 +1,91 @@
+package helpers
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// Timeouts bounds individual operations. Zero means no limit.
+type Timeouts struct {
+	Download time.Duration
+	Command  time.Duration
+}
+
+var (
+	ctxMu    sync.RWMutex
+	rootCtx  = context.Background()
+	timeouts = Timeouts{Download: 10 * time.Minute}
+
+	tempMu    sync.Mutex
+	tempFiles = map[string]struct{}{}
+)
+
+// SetContext sets the context used by helpers that are not handed one
+// explicitly. It is cancelled on Ctrl-C.
+func SetContext(ctx context.Context) {
+	ctxMu.Lock()
+	defer ctxMu.Unlock()
+	rootCtx = ctx
+}
+
+// Context returns the root context of the running command.
+func Context() context.Context {
+	ctxMu.RLock()
+	defer ctxMu.RUnlock()
+	return rootCtx
+}
+
+// SetTimeouts sets the per-operation timeouts.
+func SetTimeouts(t Timeouts) {
+	ctxMu.Lock()
+	defer ctxMu.Unlock()
+	timeouts = t
+}
+
+func currentTimeouts() Timeouts {
+	ctxMu.RLock()
+	defer ctxMu.RUnlock()
+	return timeouts
+}
+
+// withTimeout derives a context bounded by d, or returns ctx unchanged when d is zero.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// TrackTempFile registers a partial or temporary file, or a directory of
+// them, to be removed if kettle exits before the file is finished.
+func TrackTempFile(path string) {
+	tempMu.Lock()
+	defer tempMu.Unlock()
+	tempFiles[path] = struct{}{}
+}
+
+// UntrackTempFile marks a temporary file as finished or already removed.
+func UntrackTempFile(path string) {
+	tempMu.Lock()
+	defer tempMu.Unlock()
+	delete(tempFiles, path)
+}
+
+// CleanupTempFiles removes every tracked temporary file that is still around.
+func CleanupTempFiles() {
+	tempMu.Lock()
+	defer tempMu.Unlock()
+	for path := range tempFiles {
+		delete(tempFiles, path)
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			PrintError("Failed to remove partial file "+path, err)
+		} else {
+			PrintInfo("Removed partial file " + path)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// Effects is the layer every change to the machine goes through.
+// The default implementation touches the system; the dry-run recorder
+// only writes down what would have happened.
+type Effects interface {
+	Executor
+	Start(name string, args ...string) error
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	AppendFile(path string, data []byte) error
+	Download(ctx context.Context, url, dest string) error
+	Extract(ctx context.Context, archivePath, destDir, binaryName string) error
+	Chmod(path string, mode os.FileMode) error
+	Rename(oldpath, newpath string) error
+	Remove(path string) error
+	RemoveAll(path string) error
+	Symlink(target, link string) error
+	MkdirAll(path string, perm os.FileMode) error
+}
+
+var (
+	effects   Effects = observedEffects{systemEffects{}}
+	effectsMu sync.RWMutex
+	plan      *Recorder
+)
+
+// SetDryRun switches all side effects to the plan recorder when enabled.
+func SetDryRun(enabled bool) {
+	effectsMu.Lock()
+	defer effectsMu.Unlock()
+	if enabled {
+		plan = NewRecorder()
+		effects = plan
+		return
+	}
+	plan = nil
+	effects = observedEffects{systemEffects{}}
+}
+
+// IsDryRun reports whether side effects are being recorded instead of executed.
+func IsDryRun() bool {
+	effectsMu.RLock()
+	defer effectsMu.RUnlock()
+	return plan != nil
+}
+
+// CurrentEffects returns the active side-effect implementation.
+func CurrentEffects() Effects {
+	effectsMu.RLock()
+	defer effectsMu.RUnlock()
+	return effects
+}
+
+// PrintPlan prints the recorded dry-run plan, if any. Structured output
+// carries the plan in the report instead.
+func PrintPlan() {
+	effectsMu.RLock()
+	p := plan
+	effectsMu.RUnlock()
+	if p == nil || IsStructuredOutput() {
+		return
+	}
+	p.Print()
+}
+
+// WriteFile writes data to path, replacing any existing content.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	return CurrentEffects().WriteFile(path, data, perm)
+}
+
+// Chmod changes the mode of path.
+func Chmod(path string, mode os.FileMode) error {
+	return CurrentEffects().Chmod(path, mode)
+}
+
+// Rename moves oldpath to newpath.
+func Rename(oldpath, newpath string) error {
+	return CurrentEffects().Rename(oldpath, newpath)
+}
+
+// Remove deletes path.
+func Remove(path string) error {
+	return CurrentEffects().Remove(path)
+}
+
+// RemoveAll deletes path and everything under it.
+func RemoveAll(path string) error {
+	return CurrentEffects().RemoveAll(path)
+}
+
+// Symlink creates link pointing at target.
+func Symlink(target, link string) error {
+	return CurrentEffects().Symlink(target, link)
+}
+
+// MkdirAll creates path and any missing parents.
+func MkdirAll(path string, perm os.FileMode) error {
+	return CurrentEffects().MkdirAll(path, perm)
+}
+
+// StartDetached starts a process without waiting for it to exit.
+func StartDetached(name string, args ...string) error {
+	return CurrentEffects().Start(name, args...)
+}
+
+// systemEffects applies side effects to the real system.
+type systemEffects struct {
+	systemExecutor
+}
+
+func (systemEffects) Start(name string, args ...string) error {
+	return exec.Command(name, args...).Start()
+}
+
+func (systemEffects) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+
+func (systemEffects) AppendFile(path string, data []byte) (err error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer IOClose(file, &err)
+
+	_, err = file.Write(data)
+	return err
+}
+
+// Download fetches url into dest. The body is written to a .part file
+// first, so a cancelled or failed download never leaves a partial dest.
+func (systemEffects) Download(ctx context.Context, url, dest string) (err error) {
+	ctx, cancel := withTimeout(ctx, currentTimeouts().Download)
+	defer cancel()
+
+	part := dest + ".part"
+	TrackTempFile(part)
+	defer func() {
+		if err != nil {
+			_ = os.Remove(part)
+		}
+		UntrackTempFile(part)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+	defer IOClose(resp.Body, &err)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad status: %s", resp.Status)
+	}
+
+	out, err := os.Create(part)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	_, err = io.Copy(out, resp.Body)
+	IOClose(out, &err)
+	if err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+	return os.Rename(part, dest)
+}
+
+func (systemEffects) Extract(ctx context.Context, archivePath, destDir, binaryName string) error {
+	return extractBinaryFromArchive(ctx, archivePath, destDir, binaryName)
+}
+
+func (systemEffects) Chmod(path string, mode os.FileMode) error {
+	return os.Chmod(path, mode)
+}
+
+func (systemEffects) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (systemEffects) Remove(path string) error {
+	return os.Remove(path)
+}
+
+func (systemEffects) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (systemEffects) Symlink(target, link string) error {
+	return os.Symlink(target, link)
+}
+
+func (systemEffects) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// Changes returns the side effects applied so far in this run, or planned
+// so far during a dry run.
+func Changes() []Action {
+	effectsMu.RLock()
+	p := plan
+	effectsMu.RUnlock()
+	if p == nil {
+		return Actions()
+	}
+	steps := p.Steps()
+	changes := make([]Action, len(steps))
+	for i, s := range steps {
+		changes[i] = Action{Kind: s.Kind, Target: s.Detail}
+	}
+	return changes
+}
@@ -0,0 +1,77 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+)
+
+// Exit codes returned by kettle.
+const (
+	ExitOK               = 0
+	ExitFailed           = 1
+	ExitDeclined         = 2
+	ExitAlreadyInstalled = 3
+	ExitDrift            = 4
+	ExitInterrupted      = 130
+)
+
+var (
+	// ErrDeclined means the user, a flag or a non-interactive default said no.
+	ErrDeclined = errors.New("declined")
+	// ErrAlreadyInstalled means there was nothing to do.
+	ErrAlreadyInstalled = errors.New("already installed")
+	// ErrDependencyFailed means a tool was skipped because a tool it
+	// depends on could not be installed.
+	ErrDependencyFailed = errors.New("dependency failed")
+	// ErrVerification means downloaded content did not match its pinned
+	// checksum or signature.
+	ErrVerification = errors.New("verification failed")
+	// ErrDrift means files kettle manages were changed by hand.
+	ErrDrift = errors.New("drift detected")
+)
+
+// ExitCode maps an error returned by a command to kettle's exit code.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrDeclined):
+		return ExitDeclined
+	case errors.Is(err, ErrAlreadyInstalled):
+		return ExitAlreadyInstalled
+	case errors.Is(err, ErrDrift):
+		return ExitDrift
+	default:
+		return ExitFailed
+	}
+}
+
+// ErrorCode returns a stable, machine-readable code for err, used in
+// --output json|yaml reports.
+func ErrorCode(err error) string {
+	var exitErr *ExitError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrDeclined):
+		return "declined"
+	case errors.Is(err, ErrAlreadyInstalled):
+		return "already_installed"
+	case errors.Is(err, ErrSudoDisabled):
+		return "sudo_disabled"
+	case errors.Is(err, ErrDependencyFailed):
+		return "dependency_failed"
+	case errors.Is(err, ErrVerification):
+		return "verification_failed"
+	case errors.Is(err, ErrDrift):
+		return "drift"
+	case errors.Is(err, context.Canceled):
+		return "interrupted"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &exitErr):
+		return "command_failed"
+	default:
+		return "failed"
+	}
+}
@@ -0,0 +1,232 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command describes a process to run. Args holds the program and its
+// arguments; nothing is interpreted by a shell unless Args asks for one.
+type Command struct {
+	Args []string
+	// Env holds extra KEY=VALUE pairs added to the current environment.
+	Env   []string
+	Dir   string
+	Stdin io.Reader
+	// Stream prints output line by line while the command runs, in
+	// addition to capturing it.
+	Stream bool
+	// Sudo runs the command as root, validating sudo first if needed.
+	Sudo bool
+}
+
+// String renders the command as a shell-quoted line for display.
+func (c Command) String() string {
+	s := ShellQuote(c.Args...)
+	if c.Sudo {
+		s = "sudo " + s
+	}
+	if len(c.Env) > 0 {
+		s = ShellQuote(c.Env...) + " " + s
+	}
+	if c.Dir != "" {
+		s = fmt.Sprintf("(cd %s && %s)", ShellQuote(c.Dir), s)
+	}
+	return s
+}
+
+// Result holds the captured output of a finished command.
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Executor runs commands.
+type Executor interface {
+	Exec(ctx context.Context, c Command) (Result, error)
+}
+
+// ExitError is returned when a command runs but exits non-zero.
+type ExitError struct {
+	Command  Command
+	ExitCode int
+	Stderr   string
+}
+
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("%s exited with status %d", e.Command.String(), e.ExitCode)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		lines := strings.Split(stderr, "\n")
+		msg += ": " + lines[len(lines)-1]
+	}
+	return msg
+}
+
+// Exec runs c through the active side-effect layer and returns its output.
+func Exec(ctx context.Context, c Command) (Result, error) {
+	return CurrentEffects().Exec(ctx, c)
+}
+
+// Run runs a command with streamed output, showing the command before it
+// starts and whether it completed.
+func Run(ctx context.Context, args ...string) error {
+	return RunCommand(ctx, Command{Args: args, Stream: true})
+}
+
+// RunCommand runs c like Run.
+func RunCommand(ctx context.Context, c Command) error {
+	PrintInfo(fmt.Sprintf("Running: %s", c))
+	if _, err := Exec(ctx, c); err != nil {
+		PrintError(fmt.Sprintf("Command failed: %s", c))
+		return err
+	}
+	PrintInfo(fmt.Sprintf("Completed: %s", c))
+	return nil
+}
+
+// Probe runs a read-only command quietly. Probes always execute, even
+// during a dry run, since they only inspect the system.
+func Probe(ctx context.Context, args ...string) (Result, error) {
+	return systemExecutor{}.Exec(ctx, Command{Args: args})
+}
+
+// systemExecutor runs commands on the real system.
+type systemExecutor struct{}
+
+func (systemExecutor) Exec(ctx context.Context, c Command) (Result, error) {
+	if len(c.Args) == 0 {
+		return Result{}, errors.New("no command given")
+	}
+
+	args := c.Args
+	if c.Sudo && !IsRoot() {
+		if err := ensureSudo(ctx); err != nil {
+			return Result{}, err
+		}
+		args = append([]string{"sudo"}, args...)
+	}
+
+	ctx, cancel := withTimeout(ctx, currentTimeouts().Command)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	// Give the process a chance to clean up before it is killed.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = 5 * time.Second
+	cmd.Env = append(os.Environ(), c.Env...)
+	cmd.Dir = c.Dir
+	cmd.Stdin = c.Stdin
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	var streams []*lineWriter
+	if c.Stream {
+		out, errw := newLineWriter(PrintCmdOutput), newLineWriter(PrintCmdOutput)
+		streams = append(streams, out, errw)
+		cmd.Stdout = io.MultiWriter(&stdout, out)
+		cmd.Stderr = io.MultiWriter(&stderr, errw)
+	}
+
+	err := cmd.Run()
+	for _, w := range streams {
+		w.Flush()
+	}
+
+	res := Result{Stdout: stdout.String(), Stderr: stderr.String()}
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return res, fmt.Errorf("%s: %w", c, ctxErr)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			res.ExitCode = exitErr.ExitCode()
+			return res, &ExitError{Command: c, ExitCode: res.ExitCode, Stderr: res.Stderr}
+		}
+		return res, fmt.Errorf("failed to start command %s: %w", c, err)
+	}
+	return res, nil
+}
+
+// RunAttached runs c connected to kettle's own terminal, for commands
+// that need to talk to the user directly.
+func RunAttached(ctx context.Context, c Command) error {
+	cmd := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...)
+	cmd.Env = append(os.Environ(), c.Env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// lineWriter hands complete lines to emit as they are written.
+type lineWriter struct {
+	mu   sync.Mutex
+	buf  []byte
+	emit func(string)
+}
+
+func newLineWriter(emit func(string)) *lineWriter {
+	return &lineWriter{emit: emit}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush emits any trailing output that did not end in a newline.
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
+
+// ShellQuote joins args into a single line that a POSIX shell would split
+// back into the same arguments.
+func ShellQuote(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func quoteArg(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -3,25 +3,77 @@ package helpers
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
+	log "github.com/charmbracelet/log"
 	"github.com/google/go-github/github"
+	"github.com/kettleofketchup/kettle/src/internal/config"
 )
 
-func GithubDownloadLatestRelease(owner, repo, destDir, binaryName string) (string, error) {
-	ctx := context.Background()
+var (
+	githubTokenOnce sync.Once
+	githubTokenVal  string
+)
 
-	client := github.NewClient(nil)
+// githubToken returns the token for GitHub API requests from the source
+// set in the config, or "" to make anonymous requests.
+func githubToken(ctx context.Context) string {
+	githubTokenOnce.Do(func() {
+		gh := config.Current().GitHub
+		if gh.TokenCommand != "" {
+			res, err := Probe(ctx, "sh", "-c", gh.TokenCommand)
+			if err == nil {
+				githubTokenVal = strings.TrimSpace(res.Stdout)
+				return
+			}
+			log.Warn("github token command failed", "err", err)
+		}
+		if gh.TokenEnv != "" {
+			githubTokenVal = os.Getenv(gh.TokenEnv)
+		}
+	})
+	return githubTokenVal
+}
 
-	// Get latest release
-	release, _, err := client.Repositories.GetLatestRelease(ctx, owner, repo)
+// SetGithubAuth adds the configured GitHub token to a GitHub API request.
+func SetGithubAuth(req *http.Request) {
+	if token := githubToken(req.Context()); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// githubTransport authenticates every request it sends.
+type githubTransport struct {
+	base http.RoundTripper
+}
+
+func (t githubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	SetGithubAuth(req)
+	return t.base.RoundTrip(req)
+}
+
+func githubClient() *github.Client {
+	return github.NewClient(&http.Client{Transport: githubTransport{base: http.DefaultTransport}})
+}
+
+// GithubDownloadLatestRelease downloads the best matching asset of the latest
+// release into destDir and returns the path to the extracted binary.
+func GithubDownloadLatestRelease(ctx context.Context, owner, repo, destDir, binaryName string) (string, error) {
+	release, err := GithubGetLatestRelease(ctx, owner, repo)
 	if err != nil {
-		return "", fmt.Errorf("failed to get latest release: %w", err)
+		return "", err
 	}
+	return GithubDownloadRelease(ctx, release, destDir, binaryName)
+}
 
+// GithubDownloadRelease downloads the best matching asset of release into
+// destDir and returns the path to the extracted binary.
+func GithubDownloadRelease(ctx context.Context, release *github.RepositoryRelease, destDir, binaryName string) (string, error) {
 	// Collect all asset names and select the best one
 	var assetNames []string
 	for _, asset := range release.Assets {
@@ -31,7 +83,7 @@ func GithubDownloadLatestRelease(owner, repo, destDir, binaryName string) (strin
 	// Select the best asset using ranking
 	bestAssetName := SelectBestAsset(assetNames)
 	if bestAssetName == "" {
-		return "", fmt.Errorf("no suitable asset found for %s/%s release %s", owner, repo, release.GetTagName())
+		return "", fmt.Errorf("no suitable asset found for release %s (%s)", release.GetTagName(), release.GetHTMLURL())
 	}
 
 	// Find the download URL for the best asset
@@ -45,31 +97,14 @@ func GithubDownloadLatestRelease(owner, repo, destDir, binaryName string) (strin
 
 	assetName := bestAssetName
 
-	// Download the file
-	resp, err := http.Get(downloadURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to download asset: %w", err)
-	}
-	defer IOClose(resp.Body, &err)
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("bad status downloading asset: %s", resp.Status)
-	}
-
 	// Save to destDir
-	if err := os.MkdirAll(destDir, 0o755); err != nil {
+	if err := MkdirAll(destDir, 0o755); err != nil {
 		return "", fmt.Errorf("failed to create dir %s: %w", destDir, err)
 	}
 	destPath := filepath.Join(destDir, assetName)
 
-	out, err := os.Create(destPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create file %s: %w", destPath, err)
-	}
-	defer IOClose(out, &err)
-
-	if _, err := io.Copy(out, resp.Body); err != nil {
-		return "", fmt.Errorf("failed to save asset: %w", err)
+	if err := CurrentEffects().Download(ctx, downloadURL, destPath); err != nil {
+		return "", fmt.Errorf("failed to download asset: %w", err)
 	}
 
 	PrintSuccess(destPath + " downloaded successfully")
@@ -77,16 +112,19 @@ func GithubDownloadLatestRelease(owner, repo, destDir, binaryName string) (strin
 	// Check if the downloaded file is an archive and extract if needed
 	if isArchive(assetName) {
 		PrintInfo("Extracting binary from archive...")
+		// The archive is only an intermediate; drop it if we get interrupted.
+		TrackTempFile(destPath)
 
 		// Extract the binary from the archive
-		if err := ExtractBinaryFromArchive(destPath, destDir, binaryName); err != nil {
+		if err := ExtractBinaryFromArchive(ctx, destPath, destDir, binaryName); err != nil {
 			return "", fmt.Errorf("failed to extract binary from archive: %w", err)
 		}
 
 		// Remove the archive file after extraction
-		if err := os.Remove(destPath); err != nil {
+		if err := Remove(destPath); err != nil {
 			PrintError("Failed to remove archive file", err)
 		}
+		UntrackTempFile(destPath)
 
 		// Return the path to the extracted binary
 		finalBinaryPath := filepath.Join(destDir, binaryName)
@@ -97,10 +135,10 @@ func GithubDownloadLatestRelease(owner, repo, destDir, binaryName string) (strin
 	// If it's already a binary, rename it to the expected binary name
 	finalBinaryPath := filepath.Join(destDir, binaryName)
 	if destPath != finalBinaryPath {
-		if err := os.Rename(destPath, finalBinaryPath); err != nil {
+		if err := Rename(destPath, finalBinaryPath); err != nil {
 			return "", fmt.Errorf("failed to rename binary: %w", err)
 		}
-		if err := os.Chmod(finalBinaryPath, 0755); err != nil {
+		if err := Chmod(finalBinaryPath, 0755); err != nil {
 			return "", fmt.Errorf("failed to make binary executable: %w", err)
 		}
 	}
@@ -109,9 +147,8 @@ func GithubDownloadLatestRelease(owner, repo, destDir, binaryName string) (strin
 }
 
 // GithubGetLatestRelease gets the latest release information from a GitHub repository
-func GithubGetLatestRelease(owner, repo string) (*github.RepositoryRelease, error) {
-	ctx := context.Background()
-	client := github.NewClient(nil)
+func GithubGetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	client := githubClient()
 
 	release, _, err := client.Repositories.GetLatestRelease(ctx, owner, repo)
 	if err != nil {
@@ -120,3 +157,49 @@ func GithubGetLatestRelease(owner, repo string) (*github.RepositoryRelease, erro
 
 	return release, nil
 }
+
+// GithubGetReleaseByTag gets the release of a GitHub repository tagged tag.
+func GithubGetReleaseByTag(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	client := githubClient()
+
+	release, _, err := client.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release %s of %s/%s: %w", tag, owner, repo, err)
+	}
+
+	return release, nil
+}
+
+// GithubListReleases returns the most recent releases of a GitHub
+// repository, newest first, including drafts visible to the token and
+// prereleases.
+func GithubListReleases(ctx context.Context, owner, repo string) ([]*github.RepositoryRelease, error) {
+	client := githubClient()
+
+	releases, _, err := client.Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases of %s/%s: %w", owner, repo, err)
+	}
+
+	return releases, nil
+}
+
+// MatchRelease returns the newest release whose tag VersionMatches
+// version, so 1.59 picks 1.59.1 over 1.59.0, or nil when none does. Drafts
+// never match, and prereleases only when version names one exactly.
+func MatchRelease(releases []*github.RepositoryRelease, version string) *github.RepositoryRelease {
+	var best *github.RepositoryRelease
+	for _, r := range releases {
+		tag := r.GetTagName()
+		switch {
+		case r.GetDraft(), !VersionMatches(tag, version):
+			continue
+		case r.GetPrerelease() && strings.TrimPrefix(tag, "v") != strings.TrimPrefix(version, "v"):
+			continue
+		}
+		if best == nil || CompareVersions(tag, best.GetTagName()) > 0 {
+			best = r
+		}
+	}
+	return best
+}
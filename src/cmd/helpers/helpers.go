@@ -62,74 +62,30 @@ func readOSRelease(path string) (OSRelease, error) {
 	return data, nil
 }
 
-// InstallBinary copies the current executable to a directory in the user's PATH.
-func InstallBinary(path string) {
-	// 1. Get the path of the current executable
-
-	// 2. Determine the installation directory
+// InstallBinary copies the current executable to the install directory
+// and makes sure that directory is on PATH.
+func InstallBinary(path string) error {
 	destDir, err := GetInstallDir()
 	if err != nil {
-		PrintError("", err)
-	}
-
-	destPath := filepath.Join(destDir, filepath.Base(path))
-
-	// 3. Open the source file
-	srcFile, err := os.Open(path)
-	if err != nil {
-		PrintErrors(err)
+		PrintError("could not determine install directory:", err)
+		return err
 	}
-	defer IOClose(srcFile, &err)
 
-	// 4. Create the destination file (overwrite if it exists)
-	destFile, err := os.Create(destPath)
-	if err != nil {
-		PrintError("could not create destination file:", err)
-		return
-	}
-	defer IOClose(destFile, &err)
-
-	// 5. Copy the file contents
-	_, err = io.Copy(destFile, srcFile)
-	if err != nil {
+	name := filepath.Base(path)
+	if _, err := InstallFile(Context(), path, destDir, name); err != nil {
 		PrintError("could not copy binary:", err)
-		return
+		return err
 	}
-
-	// 6. Make the destination file executable
-	err = os.Chmod(destPath, 0755)
-	if err != nil {
-		PrintError("Could not make binary executable:", err)
-		return
+	if err := EnsureInPath(destDir); err != nil {
+		return err
 	}
 
-	if !CommandExists(filepath.Base(path)) {
-		PrintError(fmt.Sprintf("Install Failed for %s", filepath.Base(path)))
-		return
+	if IsDryRun() {
+		return nil
 	}
-	PrintSuccess(fmt.Sprintf("%s Installed Successfully", filepath.Base(path)))
-}
-
-// GetInstallDir determines the correct directory for installation.
-func GetInstallDir() (string, error) {
-	// Check for ~/.local/bin
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		PrintError("could not get user home directory:", err)
-		return "", fmt.Errorf("could not get user home directory: %w", err)
+	if !CommandExists(name) && !InPath(destDir) {
+		PrintInfo(fmt.Sprintf("%s was installed to %s; open a new shell to pick up the PATH change", name, destDir))
 	}
-	localBin := filepath.Join(homeDir, ".local", "bin")
-
-	// Check if the directory exists
-	info, err := os.Stat(localBin)
-	if err == nil && info.IsDir() {
-		// Check if it's in the PATH
-		path := os.Getenv("PATH")
-		if strings.Contains(path, localBin) {
-			return localBin, nil
-		}
-	}
-
-	// Fallback to /usr/local/bin
-	return "/usr/local/bin", nil
+	PrintSuccess(fmt.Sprintf("%s Installed Successfully", name))
+	return nil
 }
@@ -0,0 +1,127 @@
+package helpers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// observedEffects wraps the system effects and notes every side effect
+// it applies, so structured output and the run log can say what kettle
+// actually did.
+type observedEffects struct {
+	inner Effects
+}
+
+func (o observedEffects) Exec(ctx context.Context, c Command) (Result, error) {
+	start := time.Now()
+	res, err := o.inner.Exec(ctx, c)
+	code := res.ExitCode
+	var exitErr *ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		// The command never ran or was killed; there is no real exit code.
+		code = -1
+	}
+	recordAction(Action{Kind: "run", Target: c.String(), ExitCode: &code, DurationMS: since(start)}, err)
+	return res, err
+}
+
+func (o observedEffects) Start(name string, args ...string) error {
+	err := o.inner.Start(name, args...)
+	recordAction(Action{Kind: "start", Target: strings.Join(append([]string{name}, args...), " ")}, err)
+	return err
+}
+
+func (o observedEffects) WriteFile(path string, data []byte, perm os.FileMode) error {
+	err := o.inner.WriteFile(path, data, perm)
+	sum := sha256.Sum256(data)
+	recordAction(Action{Kind: "write", Target: path, SHA256: hex.EncodeToString(sum[:])}, err)
+	return err
+}
+
+func (o observedEffects) AppendFile(path string, data []byte) error {
+	err := o.inner.AppendFile(path, data)
+	recordAction(Action{Kind: "append", Target: path}, err)
+	return err
+}
+
+func (o observedEffects) Download(ctx context.Context, url, dest string) error {
+	start := time.Now()
+	err := o.inner.Download(ctx, url, dest)
+	a := Action{Kind: "download", Target: fmt.Sprintf("%s -> %s", url, dest), DurationMS: since(start)}
+	if err == nil {
+		a.SHA256, _ = FileSHA256(dest)
+	}
+	recordAction(a, err)
+	return err
+}
+
+func (o observedEffects) Extract(ctx context.Context, archivePath, destDir, binaryName string) error {
+	err := o.inner.Extract(ctx, archivePath, destDir, binaryName)
+	recordAction(Action{Kind: "extract", Target: fmt.Sprintf("%s from %s into %s", binaryName, archivePath, destDir)}, err)
+	return err
+}
+
+func (o observedEffects) Chmod(path string, mode os.FileMode) error {
+	err := o.inner.Chmod(path, mode)
+	recordAction(Action{Kind: "chmod", Target: fmt.Sprintf("%o %s", mode, path)}, err)
+	return err
+}
+
+func (o observedEffects) Rename(oldpath, newpath string) error {
+	err := o.inner.Rename(oldpath, newpath)
+	recordAction(Action{Kind: "rename", Target: fmt.Sprintf("%s -> %s", oldpath, newpath)}, err)
+	return err
+}
+
+func (o observedEffects) Remove(path string) error {
+	err := o.inner.Remove(path)
+	recordAction(Action{Kind: "remove", Target: path}, err)
+	return err
+}
+
+func (o observedEffects) RemoveAll(path string) error {
+	err := o.inner.RemoveAll(path)
+	recordAction(Action{Kind: "remove", Target: path}, err)
+	return err
+}
+
+func (o observedEffects) Symlink(target, link string) error {
+	err := o.inner.Symlink(target, link)
+	recordAction(Action{Kind: "symlink", Target: fmt.Sprintf("%s -> %s", link, target)}, err)
+	return err
+}
+
+func (o observedEffects) MkdirAll(path string, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return nil
+	}
+	err := o.inner.MkdirAll(path, perm)
+	recordAction(Action{Kind: "mkdir", Target: path}, err)
+	return err
+}
+
+func since(start time.Time) int64 {
+	return time.Since(start).Milliseconds()
+}
+
+// FileSHA256 returns the hex-encoded SHA-256 of the file at path.
+func FileSHA256(path string) (sum string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer IOClose(f, &err)
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,229 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormat selects how kettle reports what it did.
+type OutputFormat string
+
+const (
+	// OutputText prints styled panels for people.
+	OutputText OutputFormat = "text"
+	// OutputJSON prints a single JSON document when the command finishes.
+	OutputJSON OutputFormat = "json"
+	// OutputYAML prints a single YAML document when the command finishes.
+	OutputYAML OutputFormat = "yaml"
+)
+
+// Result statuses reported for a tool.
+const (
+	StatusInstalled        = "installed"
+	StatusPlanned          = "planned"
+	StatusAlreadyInstalled = "already_installed"
+	StatusDeclined         = "declined"
+	StatusSkipped          = "skipped"
+	StatusFailed           = "failed"
+)
+
+// Event is a message that would have been printed in text mode.
+type Event struct {
+	Level   string `json:"level" yaml:"level"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// Action is a side effect that was applied to the machine.
+type Action struct {
+	Kind   string `json:"kind" yaml:"kind"`
+	Target string `json:"target" yaml:"target"`
+	// ExitCode is set for commands that ran.
+	ExitCode   *int   `json:"exit_code,omitempty" yaml:"exit_code,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty" yaml:"duration_ms,omitempty"`
+	SHA256     string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ErrorInfo describes a failure with a stable code scripts can match on.
+type ErrorInfo struct {
+	Code    string `json:"code" yaml:"code"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// ToolResult is the outcome of installing a single tool.
+type ToolResult struct {
+	Tool    string     `json:"tool" yaml:"tool"`
+	Status  string     `json:"status" yaml:"status"`
+	Version string     `json:"version,omitempty" yaml:"version,omitempty"`
+	Path    string     `json:"path,omitempty" yaml:"path,omitempty"`
+	Error   *ErrorInfo `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Report is the document printed at the end of a run with --output json|yaml.
+type Report struct {
+	Command  string       `json:"command" yaml:"command"`
+	Success  bool         `json:"success" yaml:"success"`
+	ExitCode int          `json:"exit_code" yaml:"exit_code"`
+	DryRun   bool         `json:"dry_run" yaml:"dry_run"`
+	Error    *ErrorInfo   `json:"error,omitempty" yaml:"error,omitempty"`
+	Results  []ToolResult `json:"results,omitempty" yaml:"results,omitempty"`
+	Data     any          `json:"data,omitempty" yaml:"data,omitempty"`
+	Actions  []Action     `json:"actions,omitempty" yaml:"actions,omitempty"`
+	Plan     []PlanStep   `json:"plan,omitempty" yaml:"plan,omitempty"`
+	Events   []Event      `json:"events,omitempty" yaml:"events,omitempty"`
+}
+
+var (
+	outputMu     sync.Mutex
+	outputFormat = OutputText
+	report       Report
+)
+
+// ParseOutputFormat validates the value of --output.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch f := OutputFormat(strings.ToLower(s)); f {
+	case OutputText, OutputJSON, OutputYAML:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q (want text, json or yaml)", s)
+	}
+}
+
+// SetOutputFormat selects the output format for the rest of the run.
+func SetOutputFormat(f OutputFormat) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	outputFormat = f
+}
+
+// IsStructuredOutput reports whether styled output is replaced by a
+// JSON or YAML report.
+func IsStructuredOutput() bool {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	return outputFormat != OutputText
+}
+
+// recordEvent adds a message to the report instead of printing it.
+// It returns false in text mode, where the caller prints as usual.
+func recordEvent(level, msg string) bool {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if outputFormat == OutputText {
+		return false
+	}
+	report.Events = append(report.Events, Event{Level: level, Message: msg})
+	return true
+}
+
+func recordAction(a Action, err error) {
+	if err != nil {
+		a.Error = err.Error()
+	}
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	report.Actions = append(report.Actions, a)
+}
+
+// Actions returns the side effects applied so far in this run.
+func Actions() []Action {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	return append([]Action(nil), report.Actions...)
+}
+
+// Results returns the tool results reported so far in this run.
+func Results() []ToolResult {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	return append([]ToolResult(nil), report.Results...)
+}
+
+// ReportResult adds the outcome of a tool install to the report.
+func ReportResult(r ToolResult) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	report.Results = append(report.Results, r)
+}
+
+// SetReportData sets the command-specific payload of the report, such as
+// the tool list printed by `kettle list`.
+func SetReportData(data any) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	report.Data = data
+}
+
+// ResultStatus maps the error returned by an install to a result status.
+func ResultStatus(err error) string {
+	if errors.Is(err, ErrDependencyFailed) {
+		return StatusSkipped
+	}
+	switch ExitCode(err) {
+	case ExitOK:
+		if IsDryRun() {
+			return StatusPlanned
+		}
+		return StatusInstalled
+	case ExitAlreadyInstalled:
+		return StatusAlreadyInstalled
+	case ExitDeclined:
+		return StatusDeclined
+	default:
+		return StatusFailed
+	}
+}
+
+// NewErrorInfo describes err for the report, or returns nil for no error.
+func NewErrorInfo(err error) *ErrorInfo {
+	if err == nil {
+		return nil
+	}
+	return &ErrorInfo{Code: ErrorCode(err), Message: err.Error()}
+}
+
+// WriteReport writes the report for the finished command in the active
+// format. It does nothing in text mode.
+func WriteReport(w io.Writer, command string, code int, runErr error) error {
+	outputMu.Lock()
+	format := outputFormat
+	r := report
+	outputMu.Unlock()
+	if format == OutputText {
+		return nil
+	}
+
+	r.Command = command
+	r.ExitCode = code
+	r.Success = code == ExitOK
+	r.Error = NewErrorInfo(runErr)
+	effectsMu.RLock()
+	if plan != nil {
+		r.DryRun = true
+		r.Plan = plan.Steps()
+	}
+	effectsMu.RUnlock()
+
+	switch format {
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		return enc.Close()
+	default:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		return nil
+	}
+}
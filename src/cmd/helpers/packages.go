@@ -0,0 +1,97 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Platform describes the machine kettle runs on.
+type Platform struct {
+	OS             string `json:"os" yaml:"os"`
+	Arch           string `json:"arch" yaml:"arch"`
+	Distro         string `json:"distro,omitempty" yaml:"distro,omitempty"`
+	Version        string `json:"version,omitempty" yaml:"version,omitempty"`
+	Shell          string `json:"shell,omitempty" yaml:"shell,omitempty"`
+	PackageManager string `json:"package_manager,omitempty" yaml:"package_manager,omitempty"`
+}
+
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Arch
+	if p.Distro != "" {
+		s += fmt.Sprintf(", %s %s", p.Distro, p.Version)
+	}
+	if p.Shell != "" {
+		s += ", shell " + p.Shell
+	}
+	if p.PackageManager != "" {
+		s += ", packages via " + p.PackageManager
+	}
+	return strings.TrimSpace(s)
+}
+
+// linuxPackageManagers are looked for in order on Linux.
+var linuxPackageManagers = []string{"apt-get", "dnf", "yum", "pacman", "zypper", "apk"}
+
+// DetectPlatform reports the OS, distribution, shell and system package
+// manager.
+func DetectPlatform(ctx context.Context) Platform {
+	p := Platform{OS: runtime.GOOS, Arch: runtime.GOARCH, Shell: GetCurrentShell()}
+	if IsDarwin() {
+		p.Distro = "macos"
+		if res, err := Probe(ctx, "sw_vers", "-productVersion"); err == nil {
+			p.Version = strings.TrimSpace(res.Stdout)
+		}
+		if _, err := exec.LookPath("brew"); err == nil {
+			p.PackageManager = "brew"
+		}
+		return p
+	}
+	if osr, err := Get(); err == nil {
+		p.Distro = osr["ID"]
+		p.Version = osr["VERSION_ID"]
+	}
+	for _, pm := range linuxPackageManagers {
+		if _, err := exec.LookPath(pm); err == nil {
+			p.PackageManager = pm
+			break
+		}
+	}
+	return p
+}
+
+// InstallPackages installs system packages with manager, as root for
+// everything but Homebrew.
+func InstallPackages(ctx context.Context, manager string, packages ...string) error {
+	var cmds []Command
+	switch manager {
+	case "brew":
+		cmds = append(cmds, Command{Args: append([]string{"brew", "install"}, packages...)})
+	case "apt-get":
+		cmds = append(cmds,
+			Command{Args: []string{"apt-get", "update", "-q"}, Sudo: true},
+			// sudo resets the environment, so the frontend is set inside it.
+			Command{Args: append([]string{"env", "DEBIAN_FRONTEND=noninteractive", "apt-get", "install", "-y", "-q"}, packages...), Sudo: true})
+	case "dnf", "yum":
+		cmds = append(cmds, Command{Args: append([]string{manager, "install", "-y"}, packages...), Sudo: true})
+	case "pacman":
+		cmds = append(cmds, Command{Args: append([]string{"pacman", "-S", "--needed", "--noconfirm"}, packages...), Sudo: true})
+	case "zypper":
+		cmds = append(cmds, Command{Args: append([]string{"zypper", "--non-interactive", "install"}, packages...), Sudo: true})
+	case "apk":
+		cmds = append(cmds, Command{Args: append([]string{"apk", "add"}, packages...), Sudo: true})
+	case "":
+		return fmt.Errorf("no supported package manager found; install %s yourself", strings.Join(packages, ", "))
+	default:
+		return fmt.Errorf("unsupported package manager: %s", manager)
+	}
+	for _, c := range cmds {
+		c.Stream = true
+		if err := RunCommand(ctx, c); err != nil {
+			return fmt.Errorf("failed to install %s: %w", strings.Join(packages, ", "), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	planHeaderStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("12")).
+			Bold(true)
+	planStepStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("12"))
+	planAddStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("10")).
+			MarginLeft(6)
+	planDelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("9")).
+			MarginLeft(6)
+)
+
+// PlanStep is a single side effect captured during a dry run.
+type PlanStep struct {
+	Kind   string   `json:"kind" yaml:"kind"`
+	Detail string   `json:"detail" yaml:"detail"`
+	Diff   []string `json:"diff,omitempty" yaml:"diff,omitempty"`
+}
+
+// Recorder implements Effects by recording each side effect instead of running it.
+type Recorder struct {
+	mu    sync.Mutex
+	steps []PlanStep
+	// pending holds the content of files written earlier in the plan so
+	// later appends and writes diff against what would be on disk.
+	pending map[string][]byte
+}
+
+// NewRecorder creates an empty plan recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{pending: make(map[string][]byte)}
+}
+
+// Steps returns a copy of the recorded plan in order.
+func (r *Recorder) Steps() []PlanStep {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]PlanStep(nil), r.steps...)
+}
+
+func (r *Recorder) add(kind, detail string, diff ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, PlanStep{Kind: kind, Detail: detail, Diff: diff})
+}
+
+// current returns what path would contain at this point of the plan.
+func (r *Recorder) current(path string) []byte {
+	r.mu.Lock()
+	data, ok := r.pending[path]
+	r.mu.Unlock()
+	if ok {
+		return data
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func (r *Recorder) setPending(path string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending[path] = data
+}
+
+func (r *Recorder) Exec(ctx context.Context, c Command) (Result, error) {
+	r.add("run", c.String())
+	return Result{}, nil
+}
+
+func (r *Recorder) Start(name string, args ...string) error {
+	r.add("start", strings.Join(append([]string{name}, args...), " "))
+	return nil
+}
+
+func (r *Recorder) WriteFile(path string, data []byte, perm os.FileMode) error {
+	old := r.current(path)
+	r.setPending(path, data)
+	r.add("write", fmt.Sprintf("%s (mode %o)", path, perm), DiffLines(old, data)...)
+	return nil
+}
+
+func (r *Recorder) AppendFile(path string, data []byte) error {
+	old := r.current(path)
+	r.setPending(path, append(append([]byte(nil), old...), data...))
+	r.add("append", path, DiffLines(nil, data)...)
+	return nil
+}
+
+func (r *Recorder) Download(ctx context.Context, url, dest string) error {
+	r.add("download", fmt.Sprintf("%s -> %s", url, dest))
+	return nil
+}
+
+func (r *Recorder) Extract(ctx context.Context, archivePath, destDir, binaryName string) error {
+	r.add("extract", fmt.Sprintf("%s from %s into %s", binaryName, archivePath, destDir))
+	return nil
+}
+
+func (r *Recorder) Chmod(path string, mode os.FileMode) error {
+	r.add("chmod", fmt.Sprintf("%o %s", mode, path))
+	return nil
+}
+
+func (r *Recorder) Rename(oldpath, newpath string) error {
+	r.add("rename", fmt.Sprintf("%s -> %s", oldpath, newpath))
+	return nil
+}
+
+func (r *Recorder) Remove(path string) error {
+	r.add("remove", path)
+	return nil
+}
+
+func (r *Recorder) RemoveAll(path string) error {
+	r.add("remove", path+" (recursive)")
+	return nil
+}
+
+func (r *Recorder) Symlink(target, link string) error {
+	r.add("symlink", fmt.Sprintf("%s -> %s", link, target))
+	return nil
+}
+
+func (r *Recorder) MkdirAll(path string, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return nil
+	}
+	r.add("mkdir", path)
+	return nil
+}
+
+// Print renders the recorded plan.
+func (r *Recorder) Print() {
+	steps := r.Steps()
+	fmt.Println(planHeaderStyle.Render(fmt.Sprintf("Dry run: %d planned change(s), nothing was executed", len(steps))))
+	for i, step := range steps {
+		fmt.Println(planStepStyle.Render(fmt.Sprintf("%3d. %-8s %s", i+1, step.Kind, step.Detail)))
+		PrintDiff(step.Diff)
+	}
+}
+
+// PrintDiff prints lines from DiffLines, removals in red and additions in
+// green.
+func PrintDiff(lines []string) {
+	for _, line := range lines {
+		if strings.HasPrefix(line, "-") {
+			fmt.Println(planDelStyle.Render(line))
+		} else {
+			fmt.Println(planAddStyle.Render(line))
+		}
+	}
+}
+
+// DiffLines returns a line-based diff between old and new content, with
+// removed lines prefixed by "-" and added lines prefixed by "+".
+// Binary content is summarised instead of diffed.
+func DiffLines(old, new []byte) []string {
+	if isBinary(old) || isBinary(new) {
+		return []string{fmt.Sprintf("+ (binary content, %d bytes)", len(new))}
+	}
+	a := splitLines(old)
+	b := splitLines(new)
+
+	// Longest common subsequence table, built from the end.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "- "+a[i])
+			i++
+		default:
+			diff = append(diff, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, "+ "+b[j])
+	}
+	return diff
+}
+
+func splitLines(data []byte) []string {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
@@ -1,8 +1,10 @@
 package helpers
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
@@ -42,46 +44,43 @@ func IsDarwin() bool {
 
 // CommandExists checks if a command is in the PATH or available as a shell function/builtin.
 func CommandExists(cmd string) bool {
-	shellInfo := GetShellInfo()
-	// First try exec.LookPath for regular binaries
-	_, err := exec.LookPath(cmd)
-	if err == nil {
+	path, ok := LookupCommand(Context(), cmd)
+	switch {
+	case !ok:
+		PrintInfo(fmt.Sprintf("Command '%s' does not exist.", cmd))
+	case path != "":
 		PrintInfo(fmt.Sprintf("Command '%s' exists as binary.", cmd))
-		return true
-	}
-	cmdStr := fmt.Sprintf("command -v %s", cmd)
-
-	err = RunCmd(cmdStr, false)
-	if err == nil {
+	default:
 		PrintInfo(fmt.Sprintf("Command '%s' exists", cmd))
-		return true
 	}
+	return ok
+}
 
-	// Use interactive shell to check for functions and builtins
-	// The -i flag ensures we load shell functions like nvm
-	cmdStr = fmt.Sprintf("%s -c 'source %s; %s'", shellInfo.ShellBinPath, shellInfo.ShellRCPath, cmdStr)
-	err = RunCmd(cmdStr)
+// LookupCommand quietly looks for cmd and returns its path when it is a
+// binary. Shell functions and builtins like nvm, which only exist once the
+// rc file is loaded, are found with an empty path.
+func LookupCommand(ctx context.Context, cmd string) (string, bool) {
+	if path, err := exec.LookPath(cmd); err == nil {
+		return path, true
+	}
+	if res, err := Probe(ctx, "sh", "-c", `command -v "$1"`, "sh", cmd); err == nil {
+		if path := strings.TrimSpace(res.Stdout); filepath.IsAbs(path) {
+			return path, true
+		}
+		return "", true
+	}
+	if GetCurrentShell() == "" {
+		return "", false
+	}
+	shellInfo, err := GetShellInfo()
 	if err != nil {
-		return false
+		return "", false
 	}
-
-	// PrintInfo(fmt.Sprintf("Command '%s' exists as shell function.", cmd))
-
-	// // Check if output is not empty and not just whitespace
-	// result := strings.TrimSpace(string(output))
-	// if len(result) > 0 {
-	// 	// Check if it's a function (functions typically contain newlines or "function" keyword)
-	// 	if strings.Contains(result, "\n") || strings.Contains(result, cmd) {
-	// 		PrintInfo(fmt.Sprintf("Command '%s' exists as shell function.", cmd))
-	// 		return true
-	// 	} else {
-	// 		PrintInfo(fmt.Sprintf("Command '%s' exists (%s).", cmd, result))
-	// 		return true
-	// 	}
-	// }
-
-	PrintInfo(fmt.Sprintf("Command '%s' does not exist.", cmd))
-	return false
+	script := fmt.Sprintf("source %s; command -v %s", ShellQuote(shellInfo.ShellRCPath), ShellQuote(cmd))
+	if _, err := Probe(ctx, shellInfo.ShellBinPath, "-c", script); err != nil {
+		return "", false
+	}
+	return "", true
 }
 
 func IsUbuntuVersion(versionPrefix string) bool {
@@ -119,10 +118,28 @@ func IsUbuntu26() bool {
 	return isUbuntu26
 }
 
-func RunCmdWithShellProfile(command string) error {
-	shellInfo := GetShellInfo()
-	// Use interactive shell to ensure profile is loaded
-	cmdStr := fmt.Sprintf("%s -c 'source %s; %s'", shellInfo.ShellBinPath, shellInfo.ShellRCPath, command)
+// RunWithShellProfile runs args inside the user's shell after sourcing their
+// profile, so shell functions such as nvm are available. Output is streamed
+// and also returned.
+func RunWithShellProfile(ctx context.Context, args ...string) (Result, error) {
+	shellInfo, err := GetShellInfo()
+	if err != nil {
+		return Result{}, err
+	}
+	script := fmt.Sprintf("source %s; %s", ShellQuote(shellInfo.ShellRCPath), ShellQuote(args...))
+	c := Command{Args: []string{shellInfo.ShellBinPath, "-c", script}, Stream: true}
 
-	return RunCmd(cmdStr)
+	PrintInfo(fmt.Sprintf("Running: %s", ShellQuote(args...)))
+	return Exec(ctx, c)
+}
+
+// ProbeWithShellProfile runs args quietly inside the user's shell after
+// sourcing their profile, like Probe.
+func ProbeWithShellProfile(ctx context.Context, args ...string) (Result, error) {
+	shellInfo, err := GetShellInfo()
+	if err != nil {
+		return Result{}, err
+	}
+	script := fmt.Sprintf("source %s; %s", ShellQuote(shellInfo.ShellRCPath), ShellQuote(args...))
+	return Probe(ctx, shellInfo.ShellBinPath, "-c", script)
 }
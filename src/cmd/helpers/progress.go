@@ -0,0 +1,243 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	log "github.com/charmbracelet/log"
+	"github.com/charmbracelet/x/term"
+)
+
+// Task states shown by Progress.
+const (
+	TaskWaiting = "waiting"
+	TaskRunning = "running"
+	TaskDone    = "done"
+	TaskFailed  = "failed"
+	TaskSkipped = "skipped"
+)
+
+var (
+	spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+	taskNameStyle    = lipgloss.NewStyle().Bold(true)
+	taskDetailStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	taskRunningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+	taskDoneStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	taskFailedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+type progressTask struct {
+	name   string
+	state  string
+	detail string
+	start  time.Time
+}
+
+// Progress shows one line per task while tasks run concurrently. On a
+// terminal the lines are redrawn in place and everything printed in the
+// meantime scrolls above them; otherwise each state change is printed as
+// an info message.
+type Progress struct {
+	mu     sync.Mutex
+	out    io.Writer
+	tasks  []*progressTask
+	width  int
+	live   bool
+	paused bool
+	drawn  int
+	frame  int
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+var (
+	progressMu     sync.Mutex
+	activeProgress *Progress
+)
+
+// NewProgress creates a progress view with a waiting line for each name.
+func NewProgress(names []string) *Progress {
+	p := &Progress{out: console()}
+	for _, name := range names {
+		p.tasks = append(p.tasks, &progressTask{name: name, state: TaskWaiting})
+	}
+	if f, ok := p.out.(*os.File); ok && term.IsTerminal(f.Fd()) &&
+		!IsStructuredOutput() && !IsQuiet() && os.Getenv("TERM") != "dumb" {
+		p.live = true
+		p.width, _, _ = term.GetSize(f.Fd())
+	}
+	return p
+}
+
+// Start shows the view. Messages printed until Stop go above it.
+func (p *Progress) Start() {
+	if !p.live {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	progressMu.Lock()
+	activeProgress = p
+	progressMu.Unlock()
+	SetOutput(p)
+	if term.IsTerminal(os.Stderr.Fd()) {
+		// Logs share the terminal, so they scroll above the view too.
+		log.SetOutput(p)
+	}
+
+	p.mu.Lock()
+	p.draw()
+	p.mu.Unlock()
+	go p.spin()
+}
+
+// Stop draws the final state and hands the terminal back.
+func (p *Progress) Stop() {
+	if !p.live {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	SetOutput(p.out)
+	log.SetOutput(os.Stderr)
+	progressMu.Lock()
+	activeProgress = nil
+	progressMu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = false
+	p.clear()
+	p.draw()
+	p.drawn = 0
+}
+
+// Set changes the state of the named task. detail is shown next to it.
+func (p *Progress) Set(name, state, detail string) {
+	p.mu.Lock()
+	for _, t := range p.tasks {
+		if t.name != name {
+			continue
+		}
+		if state == TaskRunning && t.state != TaskRunning {
+			t.start = time.Now()
+		}
+		if state != TaskRunning && !t.start.IsZero() && detail != "" {
+			detail = fmt.Sprintf("%s (%s)", detail, time.Since(t.start).Round(100*time.Millisecond))
+		}
+		t.state = state
+		t.detail = detail
+	}
+	if p.live {
+		p.clear()
+		p.draw()
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	switch state {
+	case TaskWaiting:
+	case TaskFailed:
+		PrintFail(fmt.Sprintf("%s: %s", name, detail))
+	default:
+		PrintInfo(fmt.Sprintf("%s: %s", name, detail))
+	}
+}
+
+// Write prints b above the view.
+func (p *Progress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	n, err := p.out.Write(b)
+	p.draw()
+	return n, err
+}
+
+func (p *Progress) spin() {
+	defer close(p.done)
+	ticker := time.NewTicker(120 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.frame++
+			p.clear()
+			p.draw()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// clear erases the lines drawn last time. Callers hold p.mu.
+func (p *Progress) clear() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\x1b[%dA\x1b[J", p.drawn)
+		p.drawn = 0
+	}
+}
+
+// draw renders every task below the cursor. Callers hold p.mu.
+func (p *Progress) draw() {
+	if p.paused {
+		return
+	}
+	nameWidth := 0
+	for _, t := range p.tasks {
+		nameWidth = max(nameWidth, len(t.name))
+	}
+	var b strings.Builder
+	for _, t := range p.tasks {
+		var icon string
+		switch t.state {
+		case TaskRunning:
+			icon = taskRunningStyle.Render(spinnerFrames[p.frame%len(spinnerFrames)])
+		case TaskDone:
+			icon = taskDoneStyle.Render("✓")
+		case TaskFailed:
+			icon = taskFailedStyle.Render("✗")
+		case TaskSkipped:
+			icon = taskDetailStyle.Render("-")
+		default:
+			icon = taskDetailStyle.Render("·")
+		}
+		detail := t.detail
+		if limit := p.width - nameWidth - 6; p.width > 0 && len([]rune(detail)) > limit {
+			detail = string([]rune(detail)[:max(limit-1, 0)]) + "…"
+		}
+		fmt.Fprintf(&b, "  %s %s  %s\n", icon, taskNameStyle.Render(fmt.Sprintf("%-*s", nameWidth, t.name)), taskDetailStyle.Render(detail))
+	}
+	fmt.Fprint(p.out, b.String())
+	p.drawn = len(p.tasks)
+}
+
+// pauseProgress hides the active progress view, if any, so a prompt can
+// use the terminal, and returns a function that shows it again.
+func pauseProgress() func() {
+	progressMu.Lock()
+	p := activeProgress
+	progressMu.Unlock()
+	if p == nil {
+		return func() {}
+	}
+	p.mu.Lock()
+	p.clear()
+	p.paused = true
+	p.mu.Unlock()
+	return func() {
+		p.mu.Lock()
+		p.paused = false
+		p.draw()
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,126 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/x/term"
+)
+
+// NonInteractiveEnv disables all prompts when set to 1.
+const NonInteractiveEnv = "KETTLE_NONINTERACTIVE"
+
+// PromptMode decides how yes/no prompts are answered.
+type PromptMode int
+
+const (
+	// PromptAsk asks the user, or uses the prompt's default when nobody can answer.
+	PromptAsk PromptMode = iota
+	// PromptYes answers yes to every prompt (--yes).
+	PromptYes
+	// PromptNo answers no to every prompt (--no).
+	PromptNo
+)
+
+var (
+	// settingsMu guards the prompt settings, which workers read while a
+	// prompt may be waiting for an answer.
+	settingsMu sync.Mutex
+	promptMode PromptMode
+	reinstall  bool
+
+	// promptMu gives one prompt at a time the terminal.
+	promptMu sync.Mutex
+)
+
+// SetPromptMode sets how prompts are answered for the rest of the run.
+func SetPromptMode(mode PromptMode) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	promptMode = mode
+}
+
+// SetReinstall makes install commands replace tools that are already
+// installed instead of stopping or asking, as `kettle upgrade` does.
+func SetReinstall(enabled bool) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	reinstall = enabled
+}
+
+// IsReinstall reports whether installed tools should be replaced without asking.
+func IsReinstall() bool {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	return reinstall
+}
+
+// IsInteractive reports whether a user can answer prompts: stdin is a
+// terminal and KETTLE_NONINTERACTIVE is not set.
+func IsInteractive() bool {
+	if os.Getenv(NonInteractiveEnv) == "1" {
+		return false
+	}
+	return term.IsTerminal(os.Stdin.Fd())
+}
+
+// PromptYesNo prompts the user with a yes/no question and returns true for yes, false for no.
+// With --yes or --no, or when nobody can answer, it returns without asking:
+// the flag's answer, or def.
+func PromptYesNo(question string, def bool) bool {
+	settingsMu.Lock()
+	mode := promptMode
+	settingsMu.Unlock()
+
+	switch {
+	case mode == PromptYes:
+		PrintInfo(fmt.Sprintf("%s yes (--yes)", question))
+		return true
+	case mode == PromptNo:
+		PrintInfo(fmt.Sprintf("%s no (--no)", question))
+		return false
+	case !IsInteractive():
+		PrintInfo(fmt.Sprintf("%s %s (non-interactive default)", question, yesNo(def)))
+		return def
+	}
+
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	confirm := def
+	defer pauseProgress()()
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(question).
+				Description("Do you want to continue?").
+				Value(&confirm),
+		),
+	)
+	if IsStructuredOutput() {
+		// Keep stdout clean for the report.
+		form = form.WithOutput(os.Stderr)
+	}
+
+	if err := form.Run(); err != nil {
+		PrintError("Error:", err)
+		return false
+	}
+
+	if confirm {
+		PrintInfo("Continuing...")
+	} else {
+		PrintInfo("Aborted.")
+	}
+	return confirm
+
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
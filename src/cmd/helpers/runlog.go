@@ -0,0 +1,136 @@
+package helpers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	runLogName = "runs.jsonl"
+	// runLogMaxSize is the size at which the run log is rotated.
+	runLogMaxSize = 5 << 20
+	// runLogKeep is how many rotated logs are kept next to the current one.
+	runLogKeep = 3
+)
+
+// RunRecord is one line of the run log: a single kettle invocation and
+// every side effect it applied.
+type RunRecord struct {
+	Time       time.Time    `json:"time" yaml:"time"`
+	Argv       []string     `json:"argv" yaml:"argv"`
+	Command    string       `json:"command" yaml:"command"`
+	Version    string       `json:"version" yaml:"version"`
+	Dir        string       `json:"dir,omitempty" yaml:"dir,omitempty"`
+	DryRun     bool         `json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+	ExitCode   int          `json:"exit_code" yaml:"exit_code"`
+	DurationMS int64        `json:"duration_ms" yaml:"duration_ms"`
+	Error      *ErrorInfo   `json:"error,omitempty" yaml:"error,omitempty"`
+	Results    []ToolResult `json:"results,omitempty" yaml:"results,omitempty"`
+	Actions    []Action     `json:"actions,omitempty" yaml:"actions,omitempty"`
+}
+
+// RunLogPath returns the path of the current run log.
+func RunLogPath() (string, error) {
+	dir, err := GetKettleStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, runLogName), nil
+}
+
+// AppendRunLog appends rec to the run log, rotating it once it grows past
+// runLogMaxSize. The log is written directly, even during a dry run, since
+// it records what kettle did rather than changing the machine.
+func AppendRunLog(rec RunRecord) (err error) {
+	path, err := RunLogPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() >= runLogMaxSize {
+		if err := rotateRunLog(path); err != nil {
+			return fmt.Errorf("failed to rotate run log: %w", err)
+		}
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode run record: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open run log: %w", err)
+	}
+	defer IOClose(file, &err)
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// rotateRunLog shifts runs.jsonl to runs.jsonl.1, .1 to .2 and so on,
+// dropping the oldest.
+func rotateRunLog(path string) error {
+	for i := runLogKeep - 1; i >= 1; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(path, path+".1")
+}
+
+// ReadRunLog returns the last n runs, oldest first, across the current
+// and rotated logs. n <= 0 returns every run.
+func ReadRunLog(n int) ([]RunRecord, error) {
+	path, err := RunLogPath()
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for i := runLogKeep; i >= 1; i-- {
+		files = append(files, fmt.Sprintf("%s.%d", path, i))
+	}
+	files = append(files, path)
+
+	var records []RunRecord
+	for _, f := range files {
+		recs, err := readRunLogFile(f)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, recs...)
+	}
+	if n > 0 && len(records) > n {
+		records = records[len(records)-n:]
+	}
+	return records, nil
+}
+
+func readRunLogFile(path string) (records []RunRecord, err error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run log: %w", err)
+	}
+	defer IOClose(file, &err)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), runLogMaxSize)
+	for scanner.Scan() {
+		var rec RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// Skip lines cut short by a crash rather than hiding the rest.
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
@@ -1,11 +1,14 @@
 package helpers
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+
+	"github.com/kettleofketchup/kettle/src/internal/paths"
 )
 
 type ShellInfo struct {
@@ -24,10 +27,14 @@ var (
 	cachedShell ShellInfo
 	shellOnce   sync.Once
 	shellErr    error
+
+	// profileMu serialises profile edits, so tools installing in parallel
+	// never check and append the same file at the same time.
+	profileMu sync.Mutex
 )
 
 // GetShellInfo returns cached shell information, determining it once
-func GetShellInfo() ShellInfo {
+func GetShellInfo() (ShellInfo, error) {
 	shellOnce.Do(func() {
 
 		shellPath := os.Getenv("SHELL")
@@ -50,7 +57,12 @@ func GetShellInfo() ShellInfo {
 		case "zsh":
 			shellProfilePath = filepath.Join(homeDir, ".zshrc")
 		case "fish":
-			shellProfilePath = filepath.Join(homeDir, ".config", "fish", "config.fish")
+			configHome, err := paths.ConfigHome()
+			if err != nil {
+				shellErr = err
+				return
+			}
+			shellProfilePath = filepath.Join(configHome, "fish", "config.fish")
 		default:
 			shellErr = fmt.Errorf("unsupported shell type: %s", shellType)
 			return
@@ -74,10 +86,9 @@ func GetShellInfo() ShellInfo {
 		}
 	})
 	if shellErr != nil {
-		PrintError("failed to get shell info", shellErr)
-		panic(shellErr)
+		return ShellInfo{}, fmt.Errorf("failed to get shell info: %w", shellErr)
 	}
-	return cachedShell
+	return cachedShell, nil
 }
 
 // GetCurrentShell determines the name of the currently running shell by inspecting the SHELL environment variable.
@@ -89,52 +100,75 @@ func GetCurrentShell() string {
 	return filepath.Base(shellPath)
 }
 
-func AddToPath(newPath string) {
-	shellInfo := GetShellInfo()
+func AddToPath(newPath string) error {
+	if IsSystemInstall() {
+		added, err := addPathToSystemProfiles(newPath)
+		if err != nil {
+			return fmt.Errorf("failed to update the system profile: %w", err)
+		}
+		if added {
+			PrintSuccess(fmt.Sprintf("Added %s to PATH for all users", newPath))
+		}
+		return nil
+	}
+	shellInfo, err := GetShellInfo()
+	if err != nil {
+		return err
+	}
 	line := fmt.Sprintf(`export PATH="%s:$PATH"`, newPath)
-	added := AddLineToKettleShellProfile(line)
+	added, err := AddLineToKettleShellProfile(line)
+	if err != nil {
+		return err
+	}
 	if added {
 		PrintSuccess(fmt.Sprintf("Added %s to PATH in %s", newPath, filepath.Base(shellInfo.ShellRCPath)))
 	} else {
 		PrintInfo(fmt.Sprintf("%s already in PATH in %s", newPath, filepath.Base(shellInfo.ShellRCPath)))
 	}
+	return nil
+}
+
+// addToSystemProfile adds a line written for the current shell to the
+// system profile, and reports whether it was added.
+func addToSystemProfile(line string) (bool, error) {
+	added, err := AddLineToSystemProfile(GetCurrentShell(), line)
+	if err != nil {
+		return false, fmt.Errorf("failed to update the system profile: %w", err)
+	}
+	return added, nil
 }
 
 // AddLineToShellProfile adds a given line of text to the appropriate shell profile file
-// if it does not already exist in the file.
-func AddLineToShellProfile(line string) bool {
-	shellInfo := GetShellInfo()
+// if it does not already exist in the file. With --system the line goes to
+// the system profile instead.
+func AddLineToShellProfile(line string) (bool, error) {
+	if IsSystemInstall() {
+		return addToSystemProfile(line)
+	}
+	profileMu.Lock()
+	defer profileMu.Unlock()
+	shellInfo, err := GetShellInfo()
+	if err != nil {
+		return false, err
+	}
 
 	// Check if the line already exists in the file.
 	exists, err := ExistsInFile(shellInfo.ShellRCPath, line)
 	if err != nil {
-		v := fmt.Errorf("error checking shell profile: %w", err)
-		PrintError("Failed to check shell profile", v)
-		panic(v)
+		return false, fmt.Errorf("failed to check shell profile: %w", err)
 	}
 	if exists {
 		PrintInfo(fmt.Sprintf("Configuration already exists in %s.", filepath.Base(shellInfo.ShellRCPath)))
-		return false
+		return false, nil
 	}
 
 	// If the file doesn't exist or the line isn't in it, append the line.
-	file, err := os.OpenFile(shellInfo.ShellRCPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		v := fmt.Errorf("could not open shell profile for writing: %w", err)
-		PrintError("Failed to open shell profile for writing", v)
-		panic(v)
-	}
-	defer IOClose(file, &err)
-	//defer file.Close()
-
-	if _, err := fmt.Fprintln(file, line); err != nil {
-		v := fmt.Errorf("failed to write to shell profile: %w", err)
-		PrintError("Failed to write to shell profile", v)
-		panic(v)
+	if err := CurrentEffects().AppendFile(shellInfo.ShellRCPath, []byte(line+"\n")); err != nil {
+		return false, fmt.Errorf("failed to write to shell profile: %w", err)
 	}
 
 	PrintSuccess(fmt.Sprintf("Configuration added to %s.", filepath.Base(shellInfo.ShellRCPath)))
-	return true
+	return true, nil
 }
 
 // ExistsInFile checks if a given string `content` exists within the file at `filePath`.
@@ -153,40 +187,49 @@ func ExistsInFile(filePath, content string) (bool, error) {
 	return strings.Contains(string(data), content), nil
 }
 
+// GetKettleConfigDir returns kettle's config directory, creating it if needed.
 func GetKettleConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := paths.ConfigDir()
 	if err != nil {
-		return "", fmt.Errorf("could not get user home directory: %w", err)
+		return "", err
 	}
-	configDir := filepath.Join(homeDir, ".config", "kettle")
 
 	// Create the directory if it doesn't exist
 	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(configDir, 0755); err != nil {
+		if err := MkdirAll(configDir, 0755); err != nil {
 			return "", fmt.Errorf("could not create kettle config directory: %w", err)
 		}
 	}
 	return configDir, nil
 }
 
+// GetKettleStateDir returns the directory for kettle's run log and other
+// state. It is not created.
+func GetKettleStateDir() (string, error) {
+	return paths.StateDir()
+}
+
 // EnsureKettleProfileSourced makes sure the main shell profile sources the kettle-specific profile.
-func EnsureKettleProfileSourced() bool {
-	shellInfo := GetShellInfo()
+// The system profile is sourced by login shells on its own.
+func EnsureKettleProfileSourced() (bool, error) {
+	if IsSystemInstall() {
+		return false, nil
+	}
+	shellInfo, err := GetShellInfo()
+	if err != nil {
+		return false, err
+	}
 
 	sourceCmd := fmt.Sprintf("source %s", shellInfo.KettlePath)
 
 	return AddLineToShellProfile(sourceCmd)
 }
+
+// AddToFile appends input and a trailing newline to the file at path.
 func AddToFile(input string, path string) error {
 
 	// Append the line
-	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("could not open kettle shell profile for writing: %w", err)
-	}
-	defer IOClose(file, &err)
-
-	_, err = fmt.Fprintln(file, input)
+	err := CurrentEffects().AppendFile(path, []byte(input+"\n"))
 	if err != nil {
 		PrintErrors(err)
 		return fmt.Errorf("could not open kettle shell profile for writing: %w", err)
@@ -198,93 +241,84 @@ func AddToFile(input string, path string) error {
 
 // AddToProfileIfCmdExists wraps a source or a command with a check to see if the command exists before adding it.
 // Returns if the line was added (true) or already existed (false).
-func AddToProfileIfCmdExists(line string, bin string) bool {
-	shellInfo := GetShellInfo()
+func AddToProfileIfCmdExists(line string, bin string) (bool, error) {
+	shellInfo, err := GetShellInfo()
+	if err != nil {
+		return false, err
+	}
 
 	newValLine := fmt.Sprintf("if command -v %s >/dev/null; then\n", bin)
 	newValLine += fmt.Sprintf("    %s\n", line)
 	newValLine += "fi\n"
 	exists, err := ExistsInFile(shellInfo.KettlePath, line)
 	if err != nil {
-		PrintError("Failed to check if line exists in kettle profile", err)
-		panic(err)
+		return false, fmt.Errorf("failed to check kettle shell profile: %w", err)
 	}
 	if exists {
 		PrintInfo(fmt.Sprintf("%s profile already contains %q.", shellInfo.KettlePath, line))
-		return false // Line already there, do nothing.
+		return false, nil // Line already there, do nothing.
 	}
 
 	return AddLineToKettleShellProfile(newValLine)
 
 }
 
-func GetHomeDir() string {
+func GetHomeDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		e := fmt.Errorf("could not get user home directory: %w", err)
-		PrintErrors(e)
-		panic(e)
+		return "", fmt.Errorf("could not get user home directory: %w", err)
 	}
-	return homeDir
+	return homeDir, nil
 }
 
-func GetCurrentDir() string {
-	curDir, err := os.Getwd()
-	if err != nil {
-		e := fmt.Errorf("could not get current directory: %w", err)
-		PrintErrors(e)
-		panic(e)
+// AddLineToKettleShellProfile adds a given line of text to the kettle-specific shell profile,
+// or to the system profile with --system.
+func AddLineToKettleShellProfile(line string) (bool, error) {
+	if IsSystemInstall() {
+		return addToSystemProfile(line)
 	}
-	return curDir
-
-}
+	profileMu.Lock()
+	defer profileMu.Unlock()
 
-// AddLineToKettleShellProfile adds a given line of text to the kettle-specific shell profile.
-func AddLineToKettleShellProfile(line string) bool {
-
-	shellInfo := GetShellInfo()
+	shellInfo, err := GetShellInfo()
+	if err != nil {
+		return false, err
+	}
 
 	// Check if the line already exists
 	shellExists, err := ExistsInFile(shellInfo.ShellRCPath, line)
 	if err != nil {
-		v := fmt.Errorf("error checking kettle shell profile: %w", err)
-		PrintErrors(v)
-		panic(v)
+		return false, fmt.Errorf("failed to check shell profile: %w", err)
 	}
 
 	if shellExists {
 		PrintInfo(fmt.Sprintf("Shell profile already contains %q.", line))
-		return false
+		return false, nil
 	}
 
 	kettleExists, err := ExistsInFile(shellInfo.KettlePath, line)
 	if err != nil {
-		panic(fmt.Errorf("error checking kettle shell profile: %w", err))
+		return false, fmt.Errorf("failed to check kettle shell profile: %w", err)
 	}
 
 	if kettleExists {
 		PrintInfo(fmt.Sprintf("%q already contains %q.", shellInfo.KettlePath, line))
-		return false // Line already there, do nothing.
+		return false, nil // Line already there, do nothing.
 	}
 	newLine := line + "\n"
-	err = AddToFile(newLine, shellInfo.KettlePath)
-	if err != nil {
-		v := fmt.Errorf("could not add line to kettle shell profile: %w", err)
-		PrintErrors(v)
-		panic(v)
+	if err := AddToFile(newLine, shellInfo.KettlePath); err != nil {
+		return false, fmt.Errorf("could not add line to kettle shell profile: %w", err)
 	}
 
-	return true
+	return true, nil
 }
 
 // EnsureCompletionsSourced adds the source command for completions to the kettle shell profile.
 // returns true if the line was added, false if it already existed.
-func EnsureCompletionsSourced() bool {
+func EnsureCompletionsSourced() (bool, error) {
 	configDir, err := GetKettleConfigDir()
 	if err != nil {
-		v := fmt.Errorf("could not get kettle config directory: %w", err)
-		PrintErrors(v)
-		panic(v)
+		return false, fmt.Errorf("could not get kettle config directory: %w", err)
 	}
 	shell := GetCurrentShell()
 	completionFile := filepath.Join(configDir, "completions", fmt.Sprintf("kettle.%s", shell))
@@ -294,16 +328,16 @@ func EnsureCompletionsSourced() bool {
 }
 
 // SourceShellProfile sources the user's shell profile to apply changes immediately.
-func SourceShellProfile() {
-	shellInfo := GetShellInfo()
-	sourceCmd := fmt.Sprintf("source %s", shellInfo.ShellRCPath)
-
-	err := RunCmd(sourceCmd)
+func SourceShellProfile(ctx context.Context) error {
+	shellInfo, err := GetShellInfo()
 	if err != nil {
-		v := fmt.Errorf("could not source shell profile: %w", err)
-		PrintErrors(v)
-		panic(v)
+		return err
 	}
-	PrintInfo(fmt.Sprintf("Sourced shell profile successfully (%s)", shellInfo.ShellRCPath))
+	sourceCmd := fmt.Sprintf("source %s", ShellQuote(shellInfo.ShellRCPath))
 
+	if err := Run(ctx, shellInfo.ShellBinPath, "-c", sourceCmd); err != nil {
+		return fmt.Errorf("could not source shell profile: %w", err)
+	}
+	PrintInfo(fmt.Sprintf("Sourced shell profile successfully (%s)", shellInfo.ShellRCPath))
+	return nil
 }
@@ -0,0 +1,187 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/huh"
+)
+
+// AskpassEnv marks a kettle process started by sudo as its askpass helper.
+const AskpassEnv = "KETTLE_ASKPASS"
+
+// ErrSudoDisabled is returned when a command needs root but --no-sudo is set.
+var ErrSudoDisabled = errors.New("root privileges required but sudo is disabled (--no-sudo)")
+
+var (
+	sudoMu        sync.Mutex
+	sudoValidated bool
+	noSudo        bool
+	useAskpass    bool
+)
+
+// SetNoSudo makes every command that needs root fail fast instead of prompting.
+func SetNoSudo(disabled bool) {
+	sudoMu.Lock()
+	defer sudoMu.Unlock()
+	noSudo = disabled
+}
+
+// SudoDisabled reports whether --no-sudo is in effect.
+func SudoDisabled() bool {
+	sudoMu.Lock()
+	defer sudoMu.Unlock()
+	return noSudo
+}
+
+// SetUseAskpass makes sudo prompt through kettle's own askpass helper
+// instead of reading the password from the terminal.
+func SetUseAskpass(enabled bool) {
+	sudoMu.Lock()
+	defer sudoMu.Unlock()
+	useAskpass = enabled
+}
+
+// IsRoot reports whether kettle is already running as root.
+func IsRoot() bool {
+	return os.Geteuid() == 0
+}
+
+// NeedsRoot reports whether writing to path requires root, by checking
+// whether the nearest existing parent directory is writable.
+func NeedsRoot(path string) bool {
+	if IsRoot() {
+		return false
+	}
+	dir := path
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return !canWrite(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return true
+		}
+		dir = parent
+	}
+}
+
+func canWrite(dir string) bool {
+	f, err := os.CreateTemp(dir, ".kettle-write-test-*")
+	if err != nil {
+		return false
+	}
+	name := f.Name()
+	_ = f.Close()
+	_ = os.Remove(name)
+	return true
+}
+
+// RunAsRoot runs a command with root privileges, like Run.
+func RunAsRoot(ctx context.Context, args ...string) error {
+	return RunCommand(ctx, Command{Args: args, Sudo: true, Stream: true})
+}
+
+// ensureSudo makes sure sudo has a valid timestamp, prompting the user
+// the normal way if needed, and keeps it alive for the rest of the run.
+// Calls are serialised so concurrent installs only prompt once.
+func ensureSudo(ctx context.Context) error {
+	sudoMu.Lock()
+	defer sudoMu.Unlock()
+
+	if noSudo {
+		return ErrSudoDisabled
+	}
+	if sudoValidated {
+		return nil
+	}
+
+	args := []string{"sudo", "-v"}
+	var env []string
+	switch {
+	case useAskpass:
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("could not locate kettle for askpass: %w", err)
+		}
+		args = []string{"sudo", "-A", "-v"}
+		env = []string{"SUDO_ASKPASS=" + exe, AskpassEnv + "=1"}
+	case os.Getenv("SUDO_ASKPASS") != "":
+		args = []string{"sudo", "-A", "-v"}
+	case !IsInteractive():
+		// Nobody can answer a prompt; succeed only if no password is needed.
+		args = []string{"sudo", "-n", "-v"}
+	}
+
+	PrintInfo("Root privileges are required; validating sudo...")
+	cmd := Command{Args: args, Env: env}
+	resume := pauseProgress()
+	err := RunAttached(ctx, cmd)
+	resume()
+	if err != nil {
+		return fmt.Errorf("sudo authentication failed: %w", err)
+	}
+	sudoValidated = true
+
+	go keepSudoAlive(Context())
+	return nil
+}
+
+// keepSudoAlive refreshes the sudo timestamp until ctx is done, so long
+// installs do not prompt again halfway through. If a refresh fails, the
+// timestamp has expired or been revoked, and the next command that needs
+// root validates sudo again.
+func keepSudoAlive(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := Probe(ctx, "sudo", "-n", "-v"); err != nil && ctx.Err() == nil {
+				sudoMu.Lock()
+				sudoValidated = false
+				sudoMu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// RunAskpass is the entry point used when sudo invokes kettle as its
+// askpass program. It prompts on the terminal and prints the password to
+// stdout for sudo to read; kettle itself never sees it.
+func RunAskpass(args []string) (err error) {
+	prompt := "Password for sudo"
+	if len(args) > 0 && args[0] != "" {
+		prompt = args[0]
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("askpass needs a terminal: %w", err)
+	}
+	defer IOClose(tty, &err)
+
+	var password string
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(prompt).
+				EchoMode(huh.EchoModePassword).
+				Value(&password),
+		),
+	).WithInput(tty).WithOutput(tty)
+
+	if err := form.Run(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout, password)
+	return err
+}
@@ -0,0 +1,197 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/kettleofketchup/kettle/src/internal/config"
+	"github.com/kettleofketchup/kettle/src/internal/paths"
+)
+
+// System-wide install locations used with --system.
+const (
+	SystemPrefix      = "/usr/local"
+	SystemProfile     = "/etc/profile.d/kettle.sh"
+	SystemFishProfile = "/etc/fish/conf.d/kettle.fish"
+)
+
+var (
+	installMu     sync.Mutex
+	systemInstall bool
+)
+
+// SetSystemInstall switches installs to system-wide locations shared by
+// all users, with profile snippets under /etc instead of the user's rc files.
+func SetSystemInstall(enabled bool) {
+	installMu.Lock()
+	defer installMu.Unlock()
+	systemInstall = enabled
+}
+
+// IsSystemInstall reports whether --system is in effect.
+func IsSystemInstall() bool {
+	installMu.Lock()
+	defer installMu.Unlock()
+	return systemInstall
+}
+
+// InstallPrefix returns the prefix tools are installed under: --prefix or
+// install_prefix when set, SystemPrefix with --system, and "" for the
+// user's own directories.
+func InstallPrefix() string {
+	if prefix := config.Current().InstallPrefix; prefix != "" {
+		return prefix
+	}
+	if IsSystemInstall() {
+		return SystemPrefix
+	}
+	return ""
+}
+
+// GetInstallDir determines the directory binaries are installed into:
+// <prefix>/bin when a prefix is set, otherwise the user bin directory
+// (~/.local/bin by default).
+func GetInstallDir() (string, error) {
+	if prefix := InstallPrefix(); prefix != "" {
+		return filepath.Join(prefix, "bin"), nil
+	}
+	return paths.BinDir()
+}
+
+// InPath reports whether dir is one of the entries of $PATH.
+func InPath(dir string) bool {
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(entry) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnsureInPath adds dir to PATH in the managed shell profile when it is
+// not already there, so installed binaries can be found.
+func EnsureInPath(dir string) error {
+	if InPath(dir) {
+		return nil
+	}
+	PrintInfo(fmt.Sprintf("%s is not on PATH; adding it to the shell profile", dir))
+	return AddToPath(dir)
+}
+
+// InstallFile copies src into dir as an executable named name, using sudo
+// when dir is not writable, and returns the installed path.
+func InstallFile(ctx context.Context, src, dir, name string) (string, error) {
+	dest := filepath.Join(dir, name)
+	if !NeedsRoot(dir) {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", src, err)
+		}
+		if err := MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if err := WriteFile(dest, data, 0755); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", dest, err)
+		}
+		if err := Chmod(dest, 0755); err != nil {
+			return "", fmt.Errorf("failed to make %s executable: %w", dest, err)
+		}
+		return dest, nil
+	}
+
+	c := Command{Args: []string{"install", "-D", "-m", "0755", src, dest}, Sudo: true}
+	if _, err := Exec(ctx, c); err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", dest, err)
+	}
+	return dest, nil
+}
+
+// appendPrivileged appends data to path, through sudo when the file is
+// not writable by the current user.
+func appendPrivileged(ctx context.Context, path string, data []byte) error {
+	if IsDryRun() || !NeedsRoot(path) {
+		if err := MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return CurrentEffects().AppendFile(path, data)
+	}
+	if _, err := Exec(ctx, Command{Args: []string{"mkdir", "-p", filepath.Dir(path)}, Sudo: true}); err != nil {
+		return err
+	}
+	_, err := Exec(ctx, Command{
+		Args:  []string{"tee", "-a", path},
+		Stdin: bytes.NewReader(data),
+		Sudo:  true,
+	})
+	return err
+}
+
+// addLineToFile appends line to path unless it is already there, and
+// reports whether it was added.
+func addLineToFile(path, line string) (bool, error) {
+	profileMu.Lock()
+	defer profileMu.Unlock()
+	exists, err := ExistsInFile(path, line)
+	if err != nil {
+		return false, fmt.Errorf("error checking %s: %w", path, err)
+	}
+	if exists {
+		PrintInfo(fmt.Sprintf("%q already contains %q.", path, line))
+		return false, nil
+	}
+	if err := appendPrivileged(Context(), path, []byte(line+"\n")); err != nil {
+		return false, fmt.Errorf("could not add line to %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// posixLine matches lines every POSIX shell runs the same way, such as
+// PATH exports.
+var posixLine = regexp.MustCompile(`^export [A-Za-z_][A-Za-z0-9_]*=[^\n]*$`)
+
+// shellGuards holds the variable only the given shell sets, so a line
+// meant for it can sit in the shared POSIX profile.
+var shellGuards = map[string]string{"bash": "BASH_VERSION", "zsh": "ZSH_VERSION"}
+
+// AddLineToSystemProfile adds a line written for shell to the profile the
+// login shells of every user read. Fish lines go to
+// /etc/fish/conf.d/kettle.fish. Other lines go to /etc/profile.d/kettle.sh,
+// and unless they are plain exports, only shell runs them.
+func AddLineToSystemProfile(shell, line string) (bool, error) {
+	if shell == "fish" {
+		return addLineToFile(SystemFishProfile, line)
+	}
+	if !posixLine.MatchString(line) {
+		guard, ok := shellGuards[shell]
+		if !ok {
+			return false, fmt.Errorf("cannot add a line for shell %q to %s: %q", shell, SystemProfile, line)
+		}
+		var body strings.Builder
+		for _, l := range strings.Split(strings.TrimRight(line, "\n"), "\n") {
+			if l != "" {
+				l = "    " + l
+			}
+			body.WriteString(l + "\n")
+		}
+		line = fmt.Sprintf("if [ -n \"$%s\" ]; then\n%sfi", guard, body.String())
+	}
+	return addLineToFile(SystemProfile, line)
+}
+
+// addPathToSystemProfiles puts dir on PATH for every user, in both the
+// POSIX and fish system profiles.
+func addPathToSystemProfiles(dir string) (bool, error) {
+	added, err := addLineToFile(SystemProfile, fmt.Sprintf(`export PATH="%s:$PATH"`, dir))
+	if err != nil {
+		return false, err
+	}
+	fishLine := fmt.Sprintf("fish_add_path -g %s", strings.ReplaceAll(dir, " ", `\ `))
+	fishAdded, err := addLineToFile(SystemFishProfile, fishLine)
+	return added || fishAdded, err
+}
@@ -0,0 +1,174 @@
+package helpers
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// describeSuffix matches what git describe appends to a tag: the number of
+// commits since the tag and the abbreviated commit, as in 1.2.0-3-gabc123.
+var describeSuffix = regexp.MustCompile(`^(.+)-(\d+)-g[0-9a-f]+$`)
+
+// Version is a version parsed with SemVer 2.0 rules. Missing minor and
+// patch numbers are zero, and a version from git describe is a build
+// Commits past its tag.
+type Version struct {
+	Major, Minor, Patch int
+	// Pre holds the prerelease identifiers, such as [rc 1] for 1.2.0-rc.1.
+	Pre []string
+	// Build is build metadata, which never affects precedence.
+	Build string
+	// Commits counts commits past the tag, for git describe versions.
+	Commits int
+	// parts is how many of major, minor and patch were given.
+	parts int
+}
+
+// ParseVersion parses versions such as 1.2.3, v1.2, 1.2.0-rc.1+build,
+// 1.23rc1 and 1.2.0-3-gabc123 (git describe). It reports false for
+// anything else, such as dev builds or a bare commit hash.
+func ParseVersion(s string) (Version, bool) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "v"), "V")
+	var v Version
+	s = strings.TrimSuffix(s, "-dirty")
+	if m := describeSuffix.FindStringSubmatch(s); m != nil {
+		s = m[1]
+		v.Commits, _ = strconv.Atoi(m[2])
+	}
+	s, v.Build, _ = strings.Cut(s, "+")
+	core, pre, hasPre := strings.Cut(s, "-")
+	if hasPre {
+		if pre == "" {
+			return Version{}, false
+		}
+		v.Pre = strings.Split(pre, ".")
+	}
+
+	fields := strings.Split(core, ".")
+	if len(fields) > 3 {
+		return Version{}, false
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, f := range fields {
+		digits := len(f) - len(strings.TrimLeft(f, "0123456789"))
+		if digits == 0 {
+			return Version{}, false
+		}
+		*nums[i], _ = strconv.Atoi(f[:digits])
+		if rest := f[digits:]; rest != "" {
+			// Go-style prereleases such as 1.23rc1 end the core.
+			if i != len(fields)-1 || hasPre {
+				return Version{}, false
+			}
+			v.Pre = []string{rest}
+		}
+	}
+	v.parts = len(fields)
+	return v, true
+}
+
+// Compare returns -1, 0 or 1 as v is older than, the same as or newer
+// than o.
+func (v Version) Compare(o Version) int {
+	for _, d := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if c := cmpInt(d[0], d[1]); c != 0 {
+			return c
+		}
+	}
+	if c := comparePre(v.Pre, o.Pre); c != 0 {
+		return c
+	}
+	return cmpInt(v.Commits, o.Commits)
+}
+
+// comparePre orders prerelease identifiers as SemVer 2.0 does: a release
+// is newer than any prerelease, numeric identifiers compare as numbers and
+// sort before alphanumeric ones, and a longer list wins a tie.
+func comparePre(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		na, errA := strconv.Atoi(a[i])
+		nb, errB := strconv.Atoi(b[i])
+		var c int
+		switch {
+		case errA == nil && errB == nil:
+			c = cmpInt(na, nb)
+		case errA == nil:
+			c = -1
+		case errB == nil:
+			c = 1
+		default:
+			c = strings.Compare(a[i], b[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmpInt(len(a), len(b))
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// CompareVersions compares two version strings such as "1.2.3", "v1.2" or
+// "1.2.0-rc.1" by SemVer precedence, treating git describe versions as
+// newer than their tag. Versions that do not parse, such as "dev", are
+// older than any that do.
+// Returns: -1 if v1 < v2, 0 if v1 == v2, 1 if v1 > v2
+func CompareVersions(v1, v2 string) int {
+	p1, ok1 := ParseVersion(v1)
+	p2, ok2 := ParseVersion(v2)
+	switch {
+	case ok1 && ok2:
+		return p1.Compare(p2)
+	case ok1:
+		return 1
+	case ok2:
+		return -1
+	}
+	return strings.Compare(v1, v2)
+}
+
+// VersionMatches reports whether have is the version want or a release
+// within it, so 1.23 matches 1.23.4 but neither 1.230 nor 1.24.
+func VersionMatches(have, want string) bool {
+	h, okH := ParseVersion(have)
+	w, okW := ParseVersion(want)
+	if !okH || !okW {
+		return strings.TrimPrefix(have, "v") == strings.TrimPrefix(want, "v")
+	}
+	if len(w.Pre) > 0 || w.Commits > 0 || w.parts == 3 {
+		return h.Compare(w) == 0
+	}
+	if h.Major != w.Major {
+		return false
+	}
+	return w.parts < 2 || h.Minor == w.Minor
+}
+
+// NewestMatch returns the newest of versions that VersionMatches want, or
+// "" when none does.
+func NewestMatch(versions []string, want string) string {
+	var best string
+	for _, v := range versions {
+		if VersionMatches(v, want) && (best == "" || CompareVersions(v, best) > 0) {
+			best = v
+		}
+	}
+	return best
+}
@@ -0,0 +1,248 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/kettleofketchup/kettle/src/internal/paths"
+)
+
+// KeepVersions is how many versions of each tool are kept for rollback.
+const KeepVersions = 3
+
+// VersionsDir returns where the kept versions of tool live, one directory
+// per version: ~/.local/share/kettle/versions/<tool>/<version>.
+func VersionsDir(tool string) (string, error) {
+	dir, err := paths.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "versions", tool), nil
+}
+
+// KeptVersions returns the versions of tool in its versions directory,
+// newest first.
+func KeptVersions(tool string) ([]string, error) {
+	dir, err := VersionsDir(tool)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	var versions []string
+	for _, e := range entries {
+		if e.IsDir() {
+			versions = append(versions, e.Name())
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return CompareVersions(versions[i], versions[j]) > 0 })
+	return versions, nil
+}
+
+// ActiveVersion returns the kept version that binary in the install
+// directory links to, or "" when it is not a kettle-managed link.
+func ActiveVersion(tool, binary string) string {
+	installDir, err := GetInstallDir()
+	if err != nil {
+		return ""
+	}
+	versionsDir, err := VersionsDir(tool)
+	if err != nil {
+		return ""
+	}
+	target, err := os.Readlink(filepath.Join(installDir, binary))
+	if err != nil {
+		return ""
+	}
+	versionDir := filepath.Dir(target)
+	if filepath.Dir(versionDir) != versionsDir {
+		return ""
+	}
+	return filepath.Base(versionDir)
+}
+
+// KeepVersion moves the binary at src into the versions directory as
+// version of tool, makes it the active version and prunes old versions.
+// It returns the path of the link in the install directory.
+func KeepVersion(tool, version, src, binary string) (string, error) {
+	versionsDir, err := VersionsDir(tool)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(versionsDir, version)
+	if err := MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	dest := filepath.Join(dir, binary)
+	if err := Rename(src, dest); err != nil {
+		return "", fmt.Errorf("failed to keep %s %s: %w", tool, version, err)
+	}
+	if err := Chmod(dest, 0755); err != nil {
+		return "", fmt.Errorf("failed to make %s executable: %w", dest, err)
+	}
+
+	link, err := ActivateVersion(tool, version, binary)
+	if err != nil {
+		return "", err
+	}
+	if err := pruneVersions(tool, version); err != nil {
+		PrintError(fmt.Sprintf("Failed to remove old versions of %s", tool), err)
+	}
+	return link, nil
+}
+
+// findGithubRelease returns the latest release when version is empty,
+// the release tagged with a full version such as 1.59.1, or the newest
+// release within a prefix such as 1.59, which names no tag.
+func findGithubRelease(ctx context.Context, owner, repo, version string) (*github.RepositoryRelease, error) {
+	if version == "" {
+		return GithubGetLatestRelease(ctx, owner, repo)
+	}
+	if v, ok := ParseVersion(version); !ok || v.parts == 3 || len(v.Pre) > 0 {
+		return GithubGetReleaseByTag(ctx, owner, repo, "v"+strings.TrimPrefix(version, "v"))
+	}
+	releases, err := GithubListReleases(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	release := MatchRelease(releases, version)
+	if release == nil {
+		return nil, fmt.Errorf("no release of %s/%s matches %s", owner, repo, version)
+	}
+	return release, nil
+}
+
+// InstallGithubBinary installs binary from a GitHub release of owner/repo:
+// the one tagged v<version>, or the latest release when version is "". In a
+// user install directory the binary is kept as a version of tool, so
+// `kettle rollback` and `kettle use` can switch back; a shared install
+// directory gets a plain copy. It returns the version installed.
+func InstallGithubBinary(ctx context.Context, tool, owner, repo, version, binary string) (string, error) {
+	release, err := findGithubRelease(ctx, owner, repo, version)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the %s release: %w", tool, err)
+	}
+	version = strings.TrimPrefix(release.GetTagName(), "v")
+
+	cacheDir, err := paths.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	downloadDir := filepath.Join(cacheDir, tool+"-"+version)
+	TrackTempFile(downloadDir)
+	defer func() {
+		if err := RemoveAll(downloadDir); err != nil {
+			PrintError("Failed to remove "+downloadDir, err)
+		}
+		UntrackTempFile(downloadDir)
+	}()
+	PrintInfo(fmt.Sprintf("Downloading %s %s...", tool, version))
+	src, err := GithubDownloadRelease(ctx, release, downloadDir, binary)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s %s: %w", tool, version, err)
+	}
+
+	installDir, err := GetInstallDir()
+	if err != nil {
+		return "", err
+	}
+	if NeedsRoot(installDir) {
+		// Kept versions live in the user's data directory, so shared
+		// installs get a plain copy instead.
+		if _, err := InstallFile(ctx, src, installDir, binary); err != nil {
+			return "", err
+		}
+		return version, EnsureInPath(installDir)
+	}
+	if _, err := KeepVersion(tool, version, src, binary); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// ActivateVersion points binary in the install directory at a kept
+// version of tool. The link is replaced with an atomic rename, the same
+// way kettle replaces itself, so the tool is never missing.
+func ActivateVersion(tool, version, binary string) (string, error) {
+	versionsDir, err := VersionsDir(tool)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(versionsDir, version, binary)
+	if _, err := os.Stat(target); err != nil && !IsDryRun() {
+		return "", fmt.Errorf("%s %s is not kept in %s", tool, version, versionsDir)
+	}
+
+	installDir, err := GetInstallDir()
+	if err != nil {
+		return "", err
+	}
+	if NeedsRoot(installDir) {
+		return "", fmt.Errorf("%s is not writable; kept versions can only be switched in a user install directory", installDir)
+	}
+	if err := MkdirAll(installDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", installDir, err)
+	}
+
+	link := filepath.Join(installDir, binary)
+	tmp := link + ".kettle-new"
+	if _, err := os.Lstat(tmp); err == nil {
+		// Left behind by a switch that was interrupted.
+		if err := Remove(tmp); err != nil {
+			return "", fmt.Errorf("failed to remove %s: %w", tmp, err)
+		}
+	}
+	if err := Symlink(target, tmp); err != nil {
+		return "", fmt.Errorf("failed to link %s: %w", target, err)
+	}
+	if err := Rename(tmp, link); err != nil {
+		if err := Remove(tmp); err != nil {
+			PrintError("Failed to remove "+tmp, err)
+		}
+		return "", fmt.Errorf("failed to switch %s to %s: %w", link, version, err)
+	}
+
+	if found, err := exec.LookPath(binary); err == nil && found != link {
+		PrintInfo(fmt.Sprintf("%s comes first on PATH and hides %s", found, link))
+	}
+	if err := EnsureInPath(installDir); err != nil {
+		return "", err
+	}
+	return link, nil
+}
+
+// pruneVersions removes kept versions of tool beyond the newest
+// KeepVersions, never removing active.
+func pruneVersions(tool, active string) error {
+	versions, err := KeptVersions(tool)
+	if err != nil {
+		return err
+	}
+	versionsDir, err := VersionsDir(tool)
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for _, v := range versions {
+		if v == active || kept < KeepVersions {
+			kept++
+			continue
+		}
+		if err := RemoveAll(filepath.Join(versionsDir, v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,49 +4,26 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"time"
+
+	"github.com/kettleofketchup/kettle/src/internal/paths"
 )
 
 // DownloadFile downloads a file from the given URL and saves it to the specified path
 
-func DownloadFile(filepath string, url string) error {
-	// Create the file
-	out, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer IOClose(out, &err)
-
-	// Get the data
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
-	}
-	defer func() {
-		err := resp.Body.Close()
-		if err != nil {
-			PrintError("Failed to close response body", err)
-		}
-	}()
-	// Check server response
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
-	}
-
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to save file: %w", err)
+func DownloadFile(ctx context.Context, filepath string, url string) error {
+	if err := CurrentEffects().Download(ctx, url, filepath); err != nil {
+		return err
 	}
 
-	err = os.Chmod(filepath, 0755)
+	err := Chmod(filepath, 0755)
 	if err != nil {
 		return fmt.Errorf("failed to make file executable: %w", err)
 	}
@@ -71,22 +48,20 @@ func RankAsset(name string) int {
 	currentOS := runtime.GOOS
 	currentArch := runtime.GOARCH
 
-	// Ignore source archives completely
-	if isSourceArchive(name) {
+	// Ignore source archives and files that describe other assets completely
+	if isSourceArchive(name) || isReleaseMetadata(name) {
 		return 0
 	}
 
-	// Convert GOARCH to common naming conventions
-	arch := currentArch
-	if currentArch == "amd64" {
-		arch = "amd64" // Also matches x86_64, x64
-	}
-
-	// Check if name contains OS and architecture
+	// Check if name contains OS and architecture, under any of the names
+	// releases use for the architecture
 	hasOS := strings.Contains(name, currentOS)
-	hasArch := strings.Contains(name, arch) ||
-		strings.Contains(name, "x86_64") ||
-		strings.Contains(name, "x64")
+	hasArch := false
+	for _, arch := range archNames(currentArch) {
+		if strings.Contains(name, arch) {
+			hasArch = true
+		}
+	}
 
 	// Must match architecture to be considered
 	if !hasArch {
@@ -138,6 +113,19 @@ func RankAsset(name string) int {
 	return rank
 }
 
+// archNames returns the names release assets use for goarch.
+func archNames(goarch string) []string {
+	switch goarch {
+	case "amd64":
+		return []string{"amd64", "x86_64", "x64"}
+	case "arm64":
+		return []string{"arm64", "aarch64"}
+	case "386":
+		return []string{"386", "i386", "i686"}
+	}
+	return []string{goarch}
+}
+
 // SelectBestAsset selects the best asset from a list of asset names
 func SelectBestAsset(assetNames []string) string {
 	var bestAsset AssetRank
@@ -163,6 +151,17 @@ func isSourceArchive(name string) bool {
 		strings.HasPrefix(name, "v") && (strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".zip")) && !strings.Contains(name, runtime.GOOS) && !strings.Contains(name, runtime.GOARCH)
 }
 
+// isReleaseMetadata checks if the asset is a signature, checksum or other
+// file about the release rather than a build of it
+func isReleaseMetadata(name string) bool {
+	for _, suffix := range []string{".sig", ".asc", ".pem", ".sha256", ".sha256sum", ".sha512", ".md5", ".sbom", ".json", ".jsonl", ".txt"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return strings.Contains(name, "checksums") || strings.Contains(name, "sha256sums")
+}
+
 // isStandaloneBinary checks if the asset is a standalone binary
 func isStandaloneBinary(name string) bool {
 	return isExecutable(name) || (!isArchive(name) && !isPackage(name))
@@ -195,14 +194,18 @@ func isRPM(name string) bool {
 }
 
 // ExtractBinaryFromArchive extracts a binary from an archive and places it in destDir
-func ExtractBinaryFromArchive(archivePath, destDir, binaryName string) error {
+func ExtractBinaryFromArchive(ctx context.Context, archivePath, destDir, binaryName string) error {
+	return CurrentEffects().Extract(ctx, archivePath, destDir, binaryName)
+}
+
+func extractBinaryFromArchive(ctx context.Context, archivePath, destDir, binaryName string) error {
 	ext := strings.ToLower(filepath.Ext(archivePath))
 
 	switch {
 	case strings.HasSuffix(archivePath, ".tar.gz"):
-		return extractFromTarGz(archivePath, destDir, binaryName)
+		return extractFromTarGz(ctx, archivePath, destDir, binaryName)
 	case ext == ".zip":
-		return extractFromZip(archivePath, destDir, binaryName)
+		return extractFromZip(ctx, archivePath, destDir, binaryName)
 	case ext == ".deb":
 		// For .deb files, extract the binary from the data.tar.* inside
 		return extractFromDeb(archivePath, destDir, binaryName)
@@ -219,8 +222,39 @@ func ExtractBinaryFromArchive(archivePath, destDir, binaryName string) error {
 	}
 }
 
+// writeExtracted writes r to destPath through a .part file, so an
+// interrupted extraction never leaves a truncated binary behind.
+func writeExtracted(ctx context.Context, r io.Reader, destPath string) (err error) {
+	part := destPath + ".part"
+	TrackTempFile(part)
+	defer func() {
+		if err != nil {
+			_ = os.Remove(part)
+		}
+		UntrackTempFile(part)
+	}()
+
+	outFile, err := os.Create(part)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	_, err = io.Copy(outFile, r)
+	IOClose(outFile, &err)
+	if err != nil {
+		return fmt.Errorf("failed to extract binary: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(part, 0755); err != nil {
+		return fmt.Errorf("failed to make binary executable: %w", err)
+	}
+	return os.Rename(part, destPath)
+}
+
 // extractFromTarGz extracts a binary from a .tar.gz archive
-func extractFromTarGz(archivePath, destDir, binaryName string) error {
+func extractFromTarGz(ctx context.Context, archivePath, destDir, binaryName string) (err error) {
 	file, err := os.Open(archivePath)
 	if err != nil {
 		return fmt.Errorf("failed to open archive: %w", err)
@@ -246,23 +280,7 @@ func extractFromTarGz(archivePath, destDir, binaryName string) error {
 
 		// Look for the binary (could be in subdirectories)
 		if strings.HasSuffix(header.Name, binaryName) && header.Typeflag == tar.TypeReg {
-			destPath := filepath.Join(destDir, binaryName)
-			outFile, err := os.Create(destPath)
-			if err != nil {
-				return fmt.Errorf("failed to create output file: %w", err)
-			}
-
-			if _, err := io.Copy(outFile, tr); err != nil {
-				IOClose(outFile, &err)
-				return fmt.Errorf("failed to extract binary: %w", err)
-			}
-			defer IOClose(outFile, &err)
-
-			if err := os.Chmod(destPath, 0755); err != nil {
-				return fmt.Errorf("failed to make binary executable: %w", err)
-			}
-
-			return nil
+			return writeExtracted(ctx, tr, filepath.Join(destDir, binaryName))
 		}
 	}
 
@@ -270,7 +288,7 @@ func extractFromTarGz(archivePath, destDir, binaryName string) error {
 }
 
 // extractFromZip extracts a binary from a .zip archive
-func extractFromZip(archivePath, destDir, binaryName string) error {
+func extractFromZip(ctx context.Context, archivePath, destDir, binaryName string) (err error) {
 	r, err := zip.OpenReader(archivePath)
 	if err != nil {
 		return fmt.Errorf("failed to open zip archive: %w", err)
@@ -284,31 +302,9 @@ func extractFromZip(archivePath, destDir, binaryName string) error {
 				return fmt.Errorf("failed to open file in zip: %w", err)
 			}
 
-			destPath := filepath.Join(destDir, binaryName)
-			outFile, err := os.Create(destPath)
-			if err != nil {
-				err2 := rc.Close()
-				if err2 != nil {
-					PrintError("Failed to close zip file", err2)
-					return err2
-				}
-				return fmt.Errorf("failed to create output file: %w", err)
-			}
-
-			if _, err := io.Copy(outFile, rc); err != nil {
-				IOClose(outFile, &err)
-				IOClose(rc, &err)
-				return fmt.Errorf("failed to extract binary: %w", err)
-			}
-
-			IOClose(outFile, &err)
+			err = writeExtracted(ctx, rc, filepath.Join(destDir, binaryName))
 			IOClose(rc, &err)
-
-			if err := os.Chmod(destPath, 0755); err != nil {
-				return fmt.Errorf("failed to make binary executable: %w", err)
-			}
-
-			return nil
+			return err
 		}
 	}
 
@@ -323,32 +319,33 @@ func extractFromDeb(archivePath, destDir, binaryName string) error {
 	return fmt.Errorf("deb extraction not yet implemented - please use tar.gz version")
 }
 
-// Download and install a script from a url
-func DownloadAndRunInstallScript(url string, filename string) error {
-	curDir := GetCurrentDir()
-
-	shScriptPath := filepath.Join(curDir, filename)
+// DownloadAndRunInstallScript downloads a shell script from url into the
+// cache directory, runs it with sh and the given arguments, then removes
+// it.
+func DownloadAndRunInstallScript(ctx context.Context, url string, filename string, args ...string) error {
+	cacheDir, err := paths.CacheDir()
 	if err != nil {
-		PrintError("Failed to get latest NVM release info", err)
 		return err
 	}
+	if err := MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	shScriptPath := filepath.Join(cacheDir, filename)
+	TrackTempFile(shScriptPath)
+	defer func() {
+		if err := Remove(shScriptPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			PrintError("Failed to remove temporary script", err)
+		}
+		UntrackTempFile(shScriptPath)
+	}()
 
-	if err := DownloadFile(shScriptPath, url); err != nil {
+	if err := DownloadFile(ctx, shScriptPath, url); err != nil {
 		PrintError("Failed to download install script", err)
 		return err
 	}
-	const halfSecond = 500 * time.Millisecond
-	time.Sleep(halfSecond)
-
-	cmd := fmt.Sprintf("cat %s | sh -b ~/.local/bin", shScriptPath)
-	if err := RunCmd(cmd); err != nil {
+	if err := Run(ctx, append([]string{"sh", shScriptPath}, args...)...); err != nil {
 		PrintError("Failed to run install script", err)
 		return err
 	}
-	err := os.Remove(shScriptPath)
-	if err != nil {
-		PrintError("Failed to remove temporary script", err)
-		return err
-	}
 	return nil
 }
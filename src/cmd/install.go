@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -17,44 +19,50 @@ func generateCompletionForShell(shell string) error {
 	completionsDir := filepath.Join(configDir, "completions")
 
 	// Create the completions directory if it doesn't exist
-	if err := os.MkdirAll(completionsDir, 0755); err != nil {
+	if err := helpers.MkdirAll(completionsDir, 0755); err != nil {
 		return fmt.Errorf("could not create completions directory: %w", err)
 	}
 
-	// Create the completion file
-	completionFile := filepath.Join(completionsDir, fmt.Sprintf("kettle.%s", shell))
-	file, err := os.Create(completionFile)
-	if err != nil {
-		return fmt.Errorf("could not create completion file: %w", err)
-	}
-	defer helpers.IOClose(file, &err)
-
 	// Generate the completion script
+	var buf bytes.Buffer
 	switch shell {
 	case "bash":
-		if err := rootCmd.GenBashCompletion(file); err != nil {
+		if err := rootCmd.GenBashCompletion(&buf); err != nil {
 			return err
 		}
 	case "zsh":
-		if err := rootCmd.GenZshCompletion(file); err != nil {
+		if err := rootCmd.GenZshCompletion(&buf); err != nil {
 			return err
 		}
 	case "fish":
-		if err := rootCmd.GenFishCompletion(file, true); err != nil {
+		if err := rootCmd.GenFishCompletion(&buf, true); err != nil {
 			return err
 		}
 	default:
 		return fmt.Errorf("unsupported shell for completion: %s", shell)
 	}
 
+	// Write the completion file
+	completionFile := filepath.Join(completionsDir, fmt.Sprintf("kettle.%s", shell))
+	if err := helpers.WriteFile(completionFile, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("could not create completion file: %w", err)
+	}
+
 	helpers.PrintInfo(fmt.Sprintf("Generated %s completion file at %s", shell, completionFile))
 	return nil
 }
 
+// completionShells returns the shells completions are generated for.
+func completionShells() []string {
+	if configured := config.Current().Shells; len(configured) > 0 {
+		return configured
+	}
+	return []string{"bash", "zsh", "fish"}
+}
+
 // GenerateAllCompletionFiles creates completion files for all supported shells.
 func GenerateAllCompletionFiles() {
-	shells := []string{"bash", "zsh", "fish"}
-	for _, shell := range shells {
+	for _, shell := range completionShells() {
 		if err := generateCompletionForShell(shell); err != nil {
 			helpers.PrintError(fmt.Sprintf("Failed to generate %s completion", shell), err)
 		}
@@ -65,19 +73,39 @@ func GenerateAllCompletionFiles() {
 var installCmd = &cobra.Command{
 	Use:   "install",
 	Short: "Install kettle to your system",
-	Long:  `Install the kettle binary to a directory in your PATH.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		exePath, err := os.Executable()
-		if err != nil {
-			return
+	Long: `Install the kettle binary to ~/.local/bin (or $XDG_BIN_HOME), <prefix>/bin with
+--prefix, or /usr/local/bin for all users with --system, and make sure that
+directory is on PATH.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := installSelf(); err != nil {
+			return err
+		}
+		// Completions live in the installing user's config directory.
+		if helpers.IsSystemInstall() {
+			return nil
 		}
-		helpers.InstallBinary(exePath)
+		return installCompletions()
+	},
+}
 
-		GenerateAllCompletionFiles()
-		helpers.EnsureCompletionsSourced()
-		helpers.EnsureKettleProfileSourced()
+// installSelf copies the running kettle binary to the install directory.
+func installSelf() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not locate kettle: %w", err)
+	}
+	return helpers.InstallBinary(exePath)
+}
 
-	},
+// installCompletions writes the completion files and sources them, and
+// the kettle profile, from the shell profile.
+func installCompletions() error {
+	GenerateAllCompletionFiles()
+	if _, err := helpers.EnsureCompletionsSourced(); err != nil {
+		return err
+	}
+	_, err := helpers.EnsureKettleProfileSourced()
+	return err
 }
 
 func init() {
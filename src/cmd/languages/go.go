@@ -1,156 +1,246 @@
 package languages
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
-	"runtime"
+	"regexp"
+	"slices"
+	"strings"
 
-	"github.com/charmbracelet/log"
 	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
 	"github.com/spf13/cobra"
 )
 
-func addGoToPath() {
-
-	// Add ~/go/bin to PATH for Go binaries installed with 'go install'
-	helpers.PrintInfo("Adding Go workspace bin to PATH...")
-	if helpers.AddLineToKettleShellProfile(`export PATH=$PATH:$HOME/go/bin`) {
-		helpers.PrintSuccess("Added Go workspace bin to kettle shell profile")
-	}
-
-	// Ensure kettle profile is sourced
-	helpers.EnsureKettleProfileSourced()
-	// Add to PATH
-	helpers.PrintInfo("Adding Go to PATH...")
-	if !helpers.AddLineToShellProfile(`export PATH=$PATH:/usr/local/go/bin`) {
-		helpers.PrintInfo("Go already in PATH")
-		return
-	}
-	helpers.PrintSuccess("Added Go to PATH")
-
-}
-
 var goCmd = &cobra.Command{
 	Use:   "go",
-	Short: "Install Go programming language",
+	Short: "Install and switch between Go versions",
+	Long: `Install Go toolchains side by side under ~/.local/share/kettle/go/<version>
+and switch between them. The active version is linked as current, and
+current/bin is put first on PATH.
+
+Versions come from the go.dev/dl index and are checked against the SHA-256
+it publishes. A version can be a release such as 1.22.3 or 1.23rc1, or a
+prefix such as 1.22 for its newest release. Without one, install and use
+take the toolchain directive (or else the go directive) of the go.mod in
+the current directory or its parents, and install falls back to the
+latest stable release.`,
 }
 
 var goInstallCmd = &cobra.Command{
-	Use:   "install",
-	Short: "Install Go",
-	Long:  `Downloads and installs the latest version of Go.`,
-	Run: func(cmd *cobra.Command, args []string) {
-
-		if helpers.CommandExists("go") {
-			addGoToPath()
-			return
-
+	Use:   "install [version]",
+	Short: "Install a Go version and make it active",
+	Long: `Download a Go version from go.dev, verify it, install it next to the other
+installed versions and make it the active one. Without a version, the
+go.mod of the current module picks it, or else the latest stable release.`,
+	Example: `  kettle languages go install
+  kettle languages go install 1.22`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec := goModVersion()
+		if len(args) > 0 {
+			spec = args[0]
 		}
+		return installGoVersion(cmd.Context(), spec)
+	},
+}
 
-		version := "latest" // It's good practice to manage this version string
-		arch := runtime.GOARCH
-		goTarball := fmt.Sprintf("go%s.linux-%s.tar.gz", version, arch)
-		downloadURL := fmt.Sprintf("https://golang.org/dl/%s", goTarball)
+// installGo installs the latest stable Go unless kettle already installed
+// a version; a Go that kettle does not manage does not count. It is the
+// installer of kettle install and sets, which ignore go.mod, while
+// kettle languages go install always wants the latest release.
+func installGo(ctx context.Context) error {
+	if versions, _, err := installedGo(); err == nil && len(versions) > 0 && !helpers.IsReinstall() {
+		return fmt.Errorf("go: %w", helpers.ErrAlreadyInstalled)
+	}
+	return installGoVersion(ctx, "")
+}
 
-		// Download
-		helpers.PrintInfo(fmt.Sprintf("Downloading Go %s...", version))
-		if err := helpers.RunCmd(fmt.Sprintf("curl -OL %s", downloadURL)); err != nil {
-			helpers.PrintError("Failed to download Go tarball", err)
-			return
+var goUseCmd = &cobra.Command{
+	Use:   "use [version]",
+	Short: "Switch the active Go version",
+	Long: `Make an installed Go version the active one, installing it first if it is
+not installed. A prefix such as 1.22 picks the newest installed match.
+Without a version, the go.mod of the current module picks it.`,
+	Example: `  kettle languages go use 1.22.3
+  kettle languages go use`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: goVersionNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec := goModVersion()
+		if len(args) > 0 {
+			spec = args[0]
 		}
-
-		// Install
-		helpers.PrintInfo("Installing Go...")
-		installCommands := []string{
-			"sudo rm -rf /usr/local/go",
-			fmt.Sprintf("sudo tar -C /usr/local -xzf %s", goTarball),
-			fmt.Sprintf("rm %s", goTarball),
+		if spec == "" {
+			return errors.New("name a Go version; there is no go.mod asking for one")
 		}
-
-		for _, command := range installCommands {
-			if err := helpers.RunCmd(command); err != nil {
-				helpers.PrintError(fmt.Sprintf("Failed to execute command: %s", command), err)
-				return
+		spec = strings.TrimPrefix(spec, "go")
+		versions, active, err := installedGo()
+		if err != nil {
+			return err
+		}
+		for _, v := range versions {
+			if !helpers.VersionMatches(v, spec) {
+				continue
+			}
+			if v == active {
+				helpers.PrintInfo(fmt.Sprintf("Go %s is already active", v))
+				return nil
+			}
+			if err := useGoVersion(cmd.Context(), v); err != nil {
+				return err
 			}
+			helpers.PrintSuccess(fmt.Sprintf("Switched Go from %s to %s", orNone(active), v))
+			return nil
 		}
-		addGoToPath()
+		helpers.PrintInfo(fmt.Sprintf("Go %s is not installed", spec))
+		return installGoVersion(cmd.Context(), spec)
+	},
+}
 
+var goListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed Go versions",
+	Long: `List the Go versions kettle installed, newest first. The active version is
+marked with *, and the one the current go.mod asks for with (go.mod).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		versions, active, err := installedGo()
+		if err != nil {
+			return err
+		}
+		root, err := goVersionsRoot()
+		if err != nil {
+			return err
+		}
+		want := goModVersion()
+		out := make([]GoVersion, len(versions))
+		for i, v := range versions {
+			out[i] = GoVersion{Version: v, Path: filepath.Join(root, v), Active: v == active, GoMod: want != "" && helpers.VersionMatches(v, want)}
+		}
+		helpers.SetReportData(out)
+		if helpers.IsStructuredOutput() {
+			return nil
+		}
+		if len(out) == 0 {
+			helpers.PrintInfo("No Go versions are installed; run kettle languages go install")
+			return nil
+		}
+		for _, v := range out {
+			line := "  " + v.Version
+			if v.Active {
+				line = "* " + v.Version
+			}
+			if v.GoMod {
+				line += " (go.mod)"
+			}
+			fmt.Println(line)
+		}
+		if want != "" && !slices.ContainsFunc(out, func(v GoVersion) bool { return v.GoMod }) {
+			helpers.PrintInfo(fmt.Sprintf("go.mod asks for Go %s, which is not installed", want))
+		}
+		return nil
 	},
 }
 
-func addGoLintToPath() {
+// goVersionNames completes installed Go versions.
+func goVersionNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	versions, _, _ := installedGo()
+	return versions, cobra.ShellCompDirectiveNoFileComp
+}
 
-	// Add ~/go/bin to PATH for Go binaries installed with 'go install'
-	helpers.PrintInfo("Adding Go langci completions to path")
+// addGoLintCompletions loads the golangci-lint completions from the kettle
+// shell profile.
+func addGoLintCompletions() error {
+	helpers.PrintInfo("Adding golangci-lint completions to the shell profile")
 
-	shellinfo := helpers.GetShellInfo()
+	shellinfo, err := helpers.GetShellInfo()
+	if err != nil {
+		return err
+	}
 
-	added := helpers.AddToProfileIfCmdExists(fmt.Sprintf(`eval "$(golangci-lint completion %s)"`, shellinfo.Type), "golangci-lint")
+	added, err := helpers.AddToProfileIfCmdExists(fmt.Sprintf(`eval "$(golangci-lint completion %s)"`, shellinfo.Type), "golangci-lint")
+	if err != nil {
+		return err
+	}
 	if added {
 		helpers.PrintSuccess("Added golangci-lint completions to shell profile")
 	}
+	return nil
 }
-func installGoLint() {
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		helpers.PrintError("Failed to get user home directory", err)
-		return
-	}
-	if gopath := os.Getenv("GOPATH"); gopath == "" {
-		gopath = fmt.Sprintf("%s/go", homeDir)
-		err := os.Setenv("GOPATH", gopath)
-		if err != nil {
-			helpers.PrintError("Failed to set GOPATH", err)
-			return
+// installGoLint installs the latest golangci-lint, asking first when it
+// is already installed.
+func installGoLint(ctx context.Context) error {
+	if helpers.CommandExists("golangci-lint") && !helpers.IsReinstall() {
+		// Prompt user if they want to reinstall
+		if !helpers.PromptYesNo("golangci-lint is already installed. Do you want to reinstall it?", false) {
+			helpers.PrintInfo("Skipping golangci-lint installation.")
+			if err := addGoLintCompletions(); err != nil {
+				return err
+			}
+			return fmt.Errorf("golangci-lint: %w", helpers.ErrAlreadyInstalled)
 		}
+		helpers.PrintInfo("Proceeding with golangci-lint reinstallation...")
 	}
+	return installGoLintVersion(ctx, "")
+}
 
-	helpers.PrintInfo("Downloading golangci-lint install script...")
-	goPath := os.Getenv("GOPATH")
-	if goPath == "" {
-		goPath = filepath.Join(homeDir, "go")
-	}
-
-	installDir := filepath.Join(goPath, "bin")
-	helpers.AddToPath(installDir)
-	destPath, err := helpers.GithubDownloadLatestRelease("golangci", "golangci-lint", installDir, "golangci-lint")
-	helpers.PrintInfo("Downloaded golangci-lint to: " + destPath)
-
+// installGoLintVersion installs version of golangci-lint, or the latest
+// release when version is "". The previous versions are kept so
+// `kettle rollback golangci-lint` can switch back.
+func installGoLintVersion(ctx context.Context, version string) error {
+	version, err := helpers.InstallGithubBinary(ctx, "golangci-lint", "golangci", "golangci-lint", version, "golangci-lint")
 	if err != nil {
-		log.Error(destPath)
 		helpers.PrintError("Failed to install golangci-lint", err)
-		return
+		return err
 	}
-
-	helpers.PrintSuccess("golangci-lint installed successfully.")
-	addGoLintToPath()
-
+	helpers.PrintSuccess(fmt.Sprintf("golangci-lint %s installed successfully.", version))
+	return addGoLintCompletions()
 }
 
 var goLintInstallCmd = &cobra.Command{
 	Use:   "golangci-lint",
 	Short: "Install lint tool for Go",
 	Long:  `Downloads and installs the latest version of golangci-lint.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if helpers.CommandExists("golangci-lint") {
-			// Prompt user if they want to reinstall
-			if !helpers.PromptYesNo("golangci-lint is already installed. Do you want to reinstall it?") {
-				helpers.PrintInfo("Skipping golangci-lint installation.")
-				addGoLintToPath()
-				return
-			}
-			helpers.PrintInfo("Proceeding with golangci-lint reinstallation...")
-		}
-		installGoLint()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return installGoLint(cmd.Context())
 	},
 }
 
 func init() {
 	goCmd.AddCommand(goInstallCmd)
+	goCmd.AddCommand(goUseCmd)
+	goCmd.AddCommand(goListCmd)
 	goCmd.AddCommand(goLintInstallCmd)
 
-	// You'll need to add goCmd to the parent 'languages' command.
-	// Example: LanguagesCmd.AddCommand(goCmd)
+	registry.Register(registry.Tool{
+		Name:           "go",
+		Group:          "languages",
+		Description:    "The Go programming language",
+		VersionArgs:    []string{"version"},
+		VersionPattern: regexp.MustCompile(`go version go(\d+\.\d+(?:\.\d+)?\S*)`),
+		Latest:         latestGo,
+		InstallVersion: installGoVersion,
+		Versions:       installedGo,
+		UseVersion:     useGoVersion,
+		Installer:      installGo,
+		Install:        goInstallCmd,
+	})
+	registry.Register(registry.Tool{
+		Name:           "golangci-lint",
+		Group:          "languages",
+		Description:    "Linters runner for Go",
+		Deps:           []string{"go"},
+		VersionPattern: regexp.MustCompile(`has version v?(\S+)`),
+		Latest:         registry.GitHubLatest("golangci", "golangci-lint"),
+		InstallVersion: installGoLintVersion,
+		Installer:      installGoLint,
+		Install:        goLintInstallCmd,
+	})
 }
@@ -0,0 +1,282 @@
+package languages
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+)
+
+const (
+	// goIndexURL lists the supported Go releases with their files; add
+	// &include=all for every release ever made.
+	goIndexURL = "https://go.dev/dl/?mode=json"
+	goDLURL    = "https://go.dev/dl/"
+)
+
+// goRelease is a release in the go.dev/dl index.
+type goRelease struct {
+	Version string   `json:"version"`
+	Stable  bool     `json:"stable"`
+	Files   []goFile `json:"files"`
+}
+
+type goFile struct {
+	Filename string `json:"filename"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	SHA256   string `json:"sha256"`
+	Kind     string `json:"kind"`
+}
+
+// GoVersion is an installed Go toolchain.
+type GoVersion struct {
+	Version string `json:"version" yaml:"version"`
+	Path    string `json:"path" yaml:"path"`
+	Active  bool   `json:"active" yaml:"active"`
+	// GoMod is set when the go.mod of the current module asks for it.
+	GoMod bool `json:"go_mod,omitempty" yaml:"go_mod,omitempty"`
+}
+
+// goIndex fetches the go.dev/dl index, newest release first.
+func goIndex(ctx context.Context, all bool) ([]goRelease, error) {
+	url := goIndexURL
+	if all {
+		url += "&include=all"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Go releases: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status: %s", url, resp.Status)
+	}
+	var releases []goRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("failed to parse Go releases: %w", err)
+	}
+	return releases, nil
+}
+
+// resolveGo finds the release for spec: "" or "latest" for the latest
+// stable release, a full version such as 1.22.3 or 1.23rc1, or a prefix
+// such as 1.22 for its newest release.
+func resolveGo(ctx context.Context, spec string) (goRelease, error) {
+	spec = strings.TrimPrefix(strings.TrimPrefix(spec, "go"), "v")
+	releases, err := goIndex(ctx, spec != "" && spec != "latest")
+	if err != nil {
+		return goRelease{}, err
+	}
+	var best *goRelease
+	for i, r := range releases {
+		v := strings.TrimPrefix(r.Version, "go")
+		switch {
+		case spec == "" || spec == "latest":
+			if !r.Stable {
+				continue
+			}
+		case !helpers.VersionMatches(v, spec):
+			continue
+		}
+		if best == nil || helpers.CompareVersions(v, strings.TrimPrefix(best.Version, "go")) > 0 {
+			best = &releases[i]
+		}
+	}
+	if best == nil {
+		return goRelease{}, fmt.Errorf("no Go release matches %q (see %s)", spec, goDLURL)
+	}
+	return *best, nil
+}
+
+// archive returns the archive of r for this platform.
+func (r goRelease) archive() (goFile, error) {
+	for _, f := range r.Files {
+		if f.Kind == "archive" && f.OS == runtime.GOOS && f.Arch == runtime.GOARCH {
+			return f, nil
+		}
+	}
+	return goFile{}, fmt.Errorf("%s has no archive for %s/%s", r.Version, runtime.GOOS, runtime.GOARCH)
+}
+
+// latestGo returns the latest stable Go release.
+func latestGo(ctx context.Context) (registry.Release, error) {
+	r, err := resolveGo(ctx, "latest")
+	if err != nil {
+		return registry.Release{}, err
+	}
+	version := strings.TrimPrefix(r.Version, "go")
+	return registry.Release{
+		Version:   version,
+		Changelog: "https://go.dev/doc/devel/release#go" + version,
+	}, nil
+}
+
+// goVersionsRoot is where Go versions are installed side by side.
+func goVersionsRoot() (string, error) {
+	return versionsRoot("go")
+}
+
+// installedGo returns the installed Go versions, newest first, and the
+// active one.
+func installedGo() (versions []string, active string, err error) {
+	root, err := goVersionsRoot()
+	if err != nil {
+		return nil, "", err
+	}
+	return installedVersions(root)
+}
+
+// installGoVersion installs the Go release matching spec next to the other
+// installed versions and makes it the active one.
+func installGoVersion(ctx context.Context, spec string) error {
+	installed := func(spec string) string {
+		versions, _, err := installedGo()
+		if err != nil || helpers.IsReinstall() {
+			return ""
+		}
+		return helpers.NewestMatch(versions, spec)
+	}
+	if v := installed(strings.TrimPrefix(spec, "go")); v != "" {
+		helpers.PrintInfo(fmt.Sprintf("Go %s is already installed", v))
+		return useGoVersion(ctx, v)
+	}
+	release, err := resolveGo(ctx, spec)
+	if err != nil {
+		helpers.PrintError("Failed to look up the Go release", err)
+		return err
+	}
+	// Without a spec, the latest release may be installed already.
+	if v := installed(strings.TrimPrefix(release.Version, "go")); v != "" {
+		helpers.PrintInfo(fmt.Sprintf("Go %s is already installed", v))
+		return useGoVersion(ctx, v)
+	}
+	file, err := release.archive()
+	if err != nil {
+		return err
+	}
+	version := strings.TrimPrefix(release.Version, "go")
+	root, err := goVersionsRoot()
+	if err != nil {
+		return err
+	}
+
+	helpers.PrintInfo(fmt.Sprintf("Downloading Go %s...", version))
+	archive, cleanup, err := downloadVerified(ctx, goDLURL+file.Filename, file.Filename, file.SHA256)
+	if err != nil {
+		helpers.PrintError("Failed to download Go", err)
+		return err
+	}
+	defer cleanup()
+
+	helpers.PrintInfo(fmt.Sprintf("Installing Go %s into %s...", version, root))
+	if err := unpackVersion(ctx, root, version, archive, "go"); err != nil {
+		helpers.PrintError("Failed to install Go", err)
+		return err
+	}
+	if err := useGoVersion(ctx, version); err != nil {
+		return err
+	}
+	helpers.PrintSuccess(fmt.Sprintf("Go %s installed in %s", version, filepath.Join(root, version)))
+	return nil
+}
+
+// useGoVersion points the current link at an installed version and makes
+// sure it is on PATH.
+func useGoVersion(ctx context.Context, version string) error {
+	root, err := goVersionsRoot()
+	if err != nil {
+		return err
+	}
+	if err := linkCurrent(ctx, root, version); err != nil {
+		return err
+	}
+	return addGoToPath(filepath.Join(root, currentLink))
+}
+
+// addGoToPath puts the active Go first on PATH, ahead of any other Go, and
+// adds ~/go/bin for binaries installed with go install.
+func addGoToPath(goRoot string) error {
+	if _, err := helpers.EnsureKettleProfileSourced(); err != nil {
+		return err
+	}
+	added, err := helpers.AddLineToKettleShellProfile(`export PATH=$PATH:$HOME/go/bin`)
+	if err != nil {
+		return err
+	}
+	if added {
+		helpers.PrintSuccess("Added Go workspace bin to kettle shell profile")
+	}
+	added, err = helpers.AddLineToKettleShellProfile(fmt.Sprintf(`export PATH=%s:$PATH`, filepath.Join(goRoot, "bin")))
+	if err != nil {
+		return err
+	}
+	if added {
+		helpers.PrintSuccess("Added Go to PATH; open a new shell to use it")
+	}
+	return nil
+}
+
+// GoModToolchain returns the Go version the go.mod in dir, or the nearest
+// parent, asks for: its toolchain directive, or else its go directive. It
+// returns "" and no error when there is no go.mod or neither directive.
+func GoModToolchain(dir string) (version, gomod string, err error) {
+	for {
+		gomod = filepath.Join(dir, "go.mod")
+		f, err := os.Open(gomod)
+		if err == nil {
+			defer func() { _ = f.Close() }()
+			var goLine string
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				fields := strings.Fields(scanner.Text())
+				switch {
+				case len(fields) < 2:
+				case fields[0] == "toolchain":
+					return strings.TrimPrefix(fields[1], "go"), gomod, nil
+				case fields[0] == "go":
+					goLine = fields[1]
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return "", gomod, fmt.Errorf("failed to read %s: %w", gomod, err)
+			}
+			return goLine, gomod, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", gomod, fmt.Errorf("failed to read %s: %w", gomod, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+// goModVersion returns what the go.mod of the working directory asks for,
+// or "".
+func goModVersion() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	version, _, err := GoModToolchain(wd)
+	if err != nil {
+		helpers.PrintError("Ignoring go.mod", err)
+	}
+	return version
+}
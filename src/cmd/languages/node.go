@@ -1,23 +1,29 @@
 package languages
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
 
 	"github.com/spf13/cobra"
 )
 
-func installNVM() error {
+func installNVM(ctx context.Context) error {
 
-	if helpers.CommandExists("nvm") {
+	if helpers.CommandExists("nvm") && !helpers.IsReinstall() {
 
-		if !helpers.PromptYesNo("NVM is already installed. Do you want to reinstall it?") {
-			return nil
+		if !helpers.PromptYesNo("NVM is already installed. Do you want to reinstall it?", false) {
+			return fmt.Errorf("nvm: %w", helpers.ErrAlreadyInstalled)
 		}
 	}
-	repo, err := helpers.GithubGetLatestRelease("nvm-sh", "nvm")
+	repo, err := helpers.GithubGetLatestRelease(ctx, "nvm-sh", "nvm")
 	if err != nil {
 		helpers.PrintError("Failed to get latest NVM release", err)
 		return err
@@ -25,7 +31,7 @@ func installNVM() error {
 	tagVersion := *repo.TagName
 	url := fmt.Sprintf("https://raw.githubusercontent.com/nvm-sh/nvm/%s/install.sh", tagVersion)
 	helpers.PrintInfo(fmt.Sprintf("Downloading NVM install script from %q", url))
-	if err := helpers.DownloadAndRunInstallScript(url, "nvm_install.sh"); err != nil {
+	if err := helpers.DownloadAndRunInstallScript(ctx, url, "nvm_install.sh"); err != nil {
 		helpers.PrintError("Failed to install NVM", err)
 		return err
 	}
@@ -33,9 +39,11 @@ func installNVM() error {
 	return nil
 }
 
-func InstallNode() error {
+// installNodeNVM installs the latest LTS release of Node.js through nvm,
+// installing nvm first if needed.
+func installNodeNVM(ctx context.Context) error {
 	if !helpers.CommandExists("nvm") {
-		err := installNVM()
+		err := installNVM(ctx)
 		if err != nil {
 			return err
 		}
@@ -44,9 +52,9 @@ func InstallNode() error {
 	}
 	helpers.PrintInfo("Grabbing Latest Node.js...")
 	// Add your npm update logic here
-	err := helpers.RunCmdWithShellProfile("nvm install --lts")
+	res, err := helpers.RunWithShellProfile(ctx, "nvm", "install", "--lts")
 	if err != nil {
-		if strings.Contains(err.Error(), "is already installed") {
+		if strings.Contains(res.Stdout+res.Stderr, "is already installed") {
 			helpers.PrintInfo("Latest node is already installed.")
 		} else {
 			helpers.PrintError("Failed to install Node.js via NVM", err)
@@ -55,17 +63,16 @@ func InstallNode() error {
 
 	}
 
-	err = helpers.RunCmdWithShellProfile("nvm use --lts")
+	_, err = helpers.RunWithShellProfile(ctx, "nvm", "use", "--lts")
 	if err != nil {
 		helpers.PrintError("Failed to update Node.js via NVM", err)
 		return err
 
 	}
 	helpers.PrintSuccess("Node.js updated successfully.")
-	installNVMPath()
-	return nil
+	return installNVMPath()
 }
-func installNVMPath() {
+func installNVMPath() error {
 	scriptPath := `
 if command -v npm >/dev/null; then
     NPM_PREFIX=$(npm config get prefix 2>/dev/null)
@@ -74,46 +81,231 @@ if command -v npm >/dev/null; then
     fi
 fi
 `
-	helpers.AddLineToKettleShellProfile(scriptPath)
-
+	_, err := helpers.AddLineToKettleShellProfile(scriptPath)
+	return err
 }
 
 var nodeCmd = &cobra.Command{
 	Use:   "node",
-	Short: "Node.js and npm related tools",
+	Short: "Install and switch between Node.js versions",
+	Long: `Install Node.js versions side by side under ~/.local/share/kettle/node/<version>
+and switch between them. The active version is linked as current, and
+current/bin is put on PATH. npm installs global packages into the shared
+prefix ~/.local/share/kettle/node/global, so they survive a switch.
+
+Versions come from the nodejs.org index and are checked against the
+release's SHASUMS256.txt. A version can be a release such as 20.11.1, a
+prefix such as 20, lts, latest, an LTS codename such as iron or lts/iron,
+or a range such as >=18. Without one, install and use take the first line
+of .nvmrc or .node-version, or else engines.node of package.json, in the
+current directory or its parents, and install falls back to the latest
+LTS release.`,
 }
 
+var nodeUseNVM bool
+
 var nodeInstallCmd = &cobra.Command{
-	Use:   "node",
-	Short: "Install Node.js",
-	Long:  `Downloads and installs Node.js.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		err := InstallNode()
-		if err != nil {
-			helpers.PrintError("Failed to install Node.js", err)
-		} else {
+	Use:     "install [version]",
+	Aliases: []string{"node"},
+	Short:   "Install a Node.js version and make it active",
+	Long: `Download a Node.js version from nodejs.org, verify it against the release's
+SHASUMS256.txt, install it next to the other installed versions and make
+it the active one. Without a version, the project in the current
+directory picks it, or else the latest LTS release.
+
+With --nvm, install the latest LTS release through nvm instead, as
+earlier versions of kettle did.`,
+	Example: `  kettle languages node install
+  kettle languages node install 20
+  kettle languages node install lts/iron
+  kettle languages node install --nvm`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if nodeUseNVM {
+			if len(args) > 0 {
+				return errors.New("--nvm always installs the latest LTS release; drop the version")
+			}
+			err := installNodeNVM(cmd.Context())
+			if err != nil {
+				helpers.PrintError("Failed to install Node.js", err)
+				return err
+			}
 			helpers.PrintSuccess("Node.js installed.")
+			return nil
+		}
+		spec := nodeProjectVersion()
+		if len(args) > 0 {
+			spec = args[0]
+		}
+		return installNodeVersion(cmd.Context(), spec)
+	},
+}
+
+// installNode installs the latest LTS Node.js unless kettle already
+// installed a version; a Node.js that kettle does not manage, such as one
+// from nvm, does not count. It is the installer of kettle install and
+// sets, which ignore the project's .nvmrc and engines, while kettle
+// languages node install always wants the latest LTS release.
+func installNode(ctx context.Context) error {
+	if versions, _, err := installedNode(); err == nil && len(versions) > 0 && !helpers.IsReinstall() {
+		return fmt.Errorf("node: %w", helpers.ErrAlreadyInstalled)
+	}
+	return installNodeVersion(ctx, "")
+}
+
+var nodeUseCmd = &cobra.Command{
+	Use:   "use [version]",
+	Short: "Switch the active Node.js version",
+	Long: `Make an installed Node.js version the active one, installing it first if
+it is not installed. A prefix such as 20 picks the newest installed match.
+Without a version, the project in the current directory picks it.`,
+	Example: `  kettle languages node use 20
+  kettle languages node use`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: nodeVersionNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec := nodeProjectVersion()
+		if len(args) > 0 {
+			spec = args[0]
+		}
+		if spec == "" {
+			return errors.New("name a Node.js version; there is no .nvmrc, .node-version or package.json asking for one")
 		}
+		versions, active, err := installedNode()
+		if err != nil {
+			return err
+		}
+		for _, v := range versions {
+			if !NodeRangeMatches(v, strings.TrimPrefix(spec, "v")) {
+				continue
+			}
+			if v == active {
+				helpers.PrintInfo(fmt.Sprintf("Node.js %s is already active", v))
+				return nil
+			}
+			if err := useNodeVersion(cmd.Context(), v); err != nil {
+				return err
+			}
+			helpers.PrintSuccess(fmt.Sprintf("Switched Node.js from %s to %s", orNone(active), v))
+			return nil
+		}
+		helpers.PrintInfo(fmt.Sprintf("Node.js %s is not installed", spec))
+		return installNodeVersion(cmd.Context(), spec)
 	},
 }
 
+var nodeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed Node.js versions",
+	Long: `List the Node.js versions kettle installed, newest first. The active
+version is marked with *, and the one the current project asks for with
+the file that asks for it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		versions, active, err := installedNode()
+		if err != nil {
+			return err
+		}
+		root, err := nodeVersionsRoot()
+		if err != nil {
+			return err
+		}
+		var want, wantFile string
+		if wd, err := os.Getwd(); err == nil {
+			want, wantFile, _ = NodeVersionFile(wd)
+		}
+		out := make([]NodeVersion, len(versions))
+		for i, v := range versions {
+			out[i] = NodeVersion{Version: v, Path: filepath.Join(root, v), Active: v == active}
+		}
+		// A range such as >=18 matches many versions; mark the newest.
+		if want != "" {
+			for i := range out {
+				if NodeRangeMatches(out[i].Version, strings.TrimPrefix(want, "v")) {
+					out[i].Project = true
+					break
+				}
+			}
+		}
+		helpers.SetReportData(out)
+		if helpers.IsStructuredOutput() {
+			return nil
+		}
+		if len(out) == 0 {
+			helpers.PrintInfo("No Node.js versions are installed; run kettle languages node install")
+			return nil
+		}
+		for _, v := range out {
+			line := "  " + v.Version
+			if v.Active {
+				line = "* " + v.Version
+			}
+			if v.Project {
+				line += " (" + filepath.Base(wantFile) + ")"
+			}
+			fmt.Println(line)
+		}
+		if want != "" && !slices.ContainsFunc(out, func(v NodeVersion) bool { return v.Project }) {
+			helpers.PrintInfo(fmt.Sprintf("%s asks for Node.js %s, which is not installed", filepath.Base(wantFile), want))
+		}
+		return nil
+	},
+}
+
+// nodeVersionNames completes installed Node.js versions.
+func nodeVersionNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	versions, _, _ := installedNode()
+	return versions, cobra.ShellCompDirectiveNoFileComp
+}
+
 var nvmInstallCmd = &cobra.Command{
 	Use:   "nvm",
 	Short: "Install NVM (Node Version Manager)",
 	Long:  `Downloads and installs NVM, which allows you to install and manage multiple versions of Node.js.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		helpers.PrintInfo("Installing NVM...")
 
-		err := installNVM()
+		err := installNVM(cmd.Context())
+		if errors.Is(err, helpers.ErrAlreadyInstalled) {
+			return err
+		}
 		if err != nil {
 			helpers.PrintError("Failed to install NVM", err)
-		} else {
-			helpers.PrintSuccess("NVM installed.")
+			return err
 		}
+		helpers.PrintSuccess("NVM installed.")
+		return nil
 	},
 }
 
 func init() {
 	nodeCmd.AddCommand(nvmInstallCmd)
 	nodeCmd.AddCommand(nodeInstallCmd)
+	nodeCmd.AddCommand(nodeUseCmd)
+	nodeCmd.AddCommand(nodeListCmd)
+
+	nodeInstallCmd.Flags().BoolVar(&nodeUseNVM, "nvm", false, "Install the latest LTS release through nvm instead")
+
+	registry.Register(registry.Tool{
+		Name:        "nvm",
+		Group:       "languages",
+		Description: "Node Version Manager",
+		Latest:      registry.GitHubLatest("nvm-sh", "nvm"),
+		Installer:   installNVM,
+		Install:     nvmInstallCmd,
+	})
+	registry.Register(registry.Tool{
+		Name:           "node",
+		Group:          "languages",
+		Description:    "Node.js JavaScript runtime",
+		Latest:         latestNode,
+		InstallVersion: installNodeVersion,
+		Versions:       installedNode,
+		UseVersion:     useNodeVersion,
+		Installer:      installNode,
+		Install:        nodeInstallCmd,
+	})
 }
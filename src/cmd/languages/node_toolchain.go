@@ -0,0 +1,480 @@
+package languages
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+)
+
+const (
+	// nodeDistURL holds every Node.js release, with index.json listing
+	// them newest first and <version>/SHASUMS256.txt their checksums.
+	nodeDistURL  = "https://nodejs.org/dist/"
+	nodeIndexURL = nodeDistURL + "index.json"
+	// nodeGlobal is the npm prefix shared by every installed version, so
+	// packages installed with npm install -g survive a switch.
+	nodeGlobal = "global"
+)
+
+// nodeRelease is a release in the nodejs.org index.
+type nodeRelease struct {
+	Version string `json:"version"`
+	// LTS is false or the LTS codename.
+	LTS   any      `json:"lts"`
+	Files []string `json:"files"`
+}
+
+// codename returns the lowercase LTS codename of r, or "" when r is not
+// an LTS release.
+func (r nodeRelease) codename() string {
+	name, _ := r.LTS.(string)
+	return strings.ToLower(name)
+}
+
+// NodeVersion is an installed Node.js version.
+type NodeVersion struct {
+	Version string `json:"version" yaml:"version"`
+	Path    string `json:"path" yaml:"path"`
+	Active  bool   `json:"active" yaml:"active"`
+	LTS     string `json:"lts,omitempty" yaml:"lts,omitempty"`
+	// Project is set when the .nvmrc, .node-version or package.json of the
+	// current project asks for it.
+	Project bool `json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// nodeGet fetches url from nodejs.org.
+func nodeGet(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	return body, nil
+}
+
+// nodeIndex fetches the nodejs.org release index.
+func nodeIndex(ctx context.Context) ([]nodeRelease, error) {
+	body, err := nodeGet(ctx, nodeIndexURL)
+	if err != nil {
+		return nil, err
+	}
+	var releases []nodeRelease
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse Node.js releases: %w", err)
+	}
+	return releases, nil
+}
+
+// resolveNode finds the release for spec, in the forms nvm takes:
+//   - "", "lts" or "lts/*" for the latest LTS release
+//   - "latest", "node" or "current" for the newest release
+//   - an LTS codename such as "iron" or "lts/iron" for its newest release
+//   - a version such as 20.11.1, or a prefix such as 20 for its newest
+//     release
+//   - a range such as ">=18" or "^20.9.0", as in the engines field of
+//     package.json, for the newest LTS release in it, or else the newest
+//     release
+func resolveNode(ctx context.Context, spec string) (nodeRelease, error) {
+	releases, err := nodeIndex(ctx)
+	if err != nil {
+		return nodeRelease{}, err
+	}
+	return matchNode(releases, spec)
+}
+
+var nodeRangeOperator = regexp.MustCompile(`[<>=^~|*xX]|\s-\s`)
+
+func matchNode(releases []nodeRelease, spec string) (nodeRelease, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	codename := strings.TrimPrefix(spec, "lts/")
+	isRange := nodeRangeOperator.MatchString(spec)
+	match := func(r nodeRelease) bool {
+		v := strings.TrimPrefix(r.Version, "v")
+		switch {
+		case spec == "" || spec == "lts" || spec == "lts/*":
+			return r.codename() != ""
+		case spec == "latest" || spec == "node" || spec == "current":
+			return true
+		case codename != "" && codename == r.codename():
+			return true
+		default:
+			return NodeRangeMatches(v, spec)
+		}
+	}
+
+	var best, bestLTS *nodeRelease
+	for i, r := range releases {
+		if !match(r) {
+			continue
+		}
+		v := strings.TrimPrefix(r.Version, "v")
+		if best == nil || helpers.CompareVersions(v, strings.TrimPrefix(best.Version, "v")) > 0 {
+			best = &releases[i]
+		}
+		if r.codename() != "" && (bestLTS == nil || helpers.CompareVersions(v, strings.TrimPrefix(bestLTS.Version, "v")) > 0) {
+			bestLTS = &releases[i]
+		}
+	}
+	switch {
+	case isRange && bestLTS != nil:
+		return *bestLTS, nil
+	case best != nil:
+		return *best, nil
+	}
+	return nodeRelease{}, fmt.Errorf("no Node.js release matches %q (see %s)", spec, nodeDistURL)
+}
+
+var nodeOperatorSpace = regexp.MustCompile(`([<>=^~]+)\s+`)
+
+// NodeRangeMatches reports whether version is in rng, a version range as
+// npm writes it: comparators such as >=18.0.0, <21, ^20.9, ~20.11 or 20.x,
+// separated by spaces to require all of them, by || for alternatives, or
+// a hyphen range such as 18 - 20. A plain version such as 20 or 20.11.1
+// matches that release, or every release within it.
+func NodeRangeMatches(version, rng string) bool {
+	rng = nodeOperatorSpace.ReplaceAllString(rng, "$1")
+	for _, alt := range strings.Split(rng, "||") {
+		fields := strings.Fields(alt)
+		if len(fields) == 3 && fields[1] == "-" {
+			fields = []string{">=" + fields[0], "<=" + fields[2]}
+		}
+		if len(fields) == 0 {
+			fields = []string{"*"}
+		}
+		ok := true
+		for _, c := range fields {
+			if !nodeComparator(version, c) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeComparator reports whether version satisfies a single comparator.
+func nodeComparator(version, c string) bool {
+	i := strings.IndexFunc(c, func(r rune) bool { return !strings.ContainsRune("<>=^~", r) })
+	if i < 0 {
+		i = len(c)
+	}
+	op := c[:i]
+	parts := strings.Split(strings.TrimPrefix(c[i:], "v"), ".")
+	for n, p := range parts {
+		if p == "" || p == "x" || p == "X" || p == "*" {
+			parts = parts[:n]
+			break
+		}
+	}
+	if len(parts) == 0 {
+		return op != "<" && op != ">"
+	}
+	want := strings.Join(parts, ".")
+	cmp := helpers.CompareVersions(version, want)
+	switch op {
+	case "", "=":
+		return helpers.VersionMatches(version, want)
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0 && !helpers.VersionMatches(version, want)
+	case "<=":
+		return cmp <= 0 || helpers.VersionMatches(version, want)
+	case "<":
+		return cmp < 0
+	case "^":
+		return cmp >= 0 && helpers.VersionMatches(version, parts[0])
+	case "~":
+		return cmp >= 0 && helpers.VersionMatches(version, strings.Join(parts[:min(len(parts), 2)], "."))
+	}
+	return false
+}
+
+// NodeVersionFile returns the Node.js version the project in dir, or the
+// nearest parent that names one, asks for, and the file that says so: the
+// first line of .nvmrc or .node-version, or else the engines.node range
+// of package.json. It returns "" and no error when no project names one.
+func NodeVersionFile(dir string) (spec, path string, err error) {
+	for {
+		for _, name := range []string{".nvmrc", ".node-version"} {
+			path = filepath.Join(dir, name)
+			data, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return "", path, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				line, _, _ := strings.Cut(scanner.Text(), "#")
+				if line = strings.TrimSpace(line); line != "" {
+					return line, path, nil
+				}
+			}
+		}
+
+		path = filepath.Join(dir, "package.json")
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			var pkg struct {
+				Engines struct {
+					Node string `json:"node"`
+				} `json:"engines"`
+			}
+			if err := json.Unmarshal(data, &pkg); err != nil {
+				return "", path, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			if spec := strings.TrimSpace(pkg.Engines.Node); spec != "" {
+				return spec, path, nil
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return "", path, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+// nodeProjectVersion returns what the project in the working directory
+// asks for, or "".
+func nodeProjectVersion() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	spec, path, err := NodeVersionFile(wd)
+	if err != nil {
+		helpers.PrintError("Ignoring "+filepath.Base(path), err)
+	}
+	return spec
+}
+
+// nodePlatform returns the os-arch part of the Node.js tarball names for
+// this platform, such as linux-x64 or darwin-arm64.
+func nodePlatform() (string, error) {
+	goos := map[string]string{"linux": "linux", "darwin": "darwin"}[runtime.GOOS]
+	arch := map[string]string{"amd64": "x64", "arm64": "arm64", "arm": "armv7l", "ppc64le": "ppc64le", "s390x": "s390x"}[runtime.GOARCH]
+	if goos == "" || arch == "" {
+		return "", fmt.Errorf("nodejs.org has no tarball for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	return goos + "-" + arch, nil
+}
+
+// hasTarball reports whether r was built for platform; the index names
+// macOS builds osx-<arch>-tar.
+func (r nodeRelease) hasTarball(platform string) bool {
+	if arch, ok := strings.CutPrefix(platform, "darwin-"); ok {
+		return slices.Contains(r.Files, "osx-"+arch+"-tar")
+	}
+	return slices.Contains(r.Files, platform)
+}
+
+// nodeChecksum returns the SHA-256 of file in the SHASUMS256.txt of
+// version.
+func nodeChecksum(ctx context.Context, version, file string) (string, error) {
+	body, err := nodeGet(ctx, nodeDistURL+version+"/SHASUMS256.txt")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[1] == file {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s is not in SHASUMS256.txt for %s", helpers.ErrVerification, file, version)
+}
+
+// latestNode returns the latest LTS release of Node.js, which is what
+// kettle installs by default.
+func latestNode(ctx context.Context) (registry.Release, error) {
+	r, err := resolveNode(ctx, "lts")
+	if err != nil {
+		return registry.Release{}, err
+	}
+	return registry.Release{
+		Version:   strings.TrimPrefix(r.Version, "v"),
+		Changelog: "https://github.com/nodejs/node/releases/tag/" + r.Version,
+	}, nil
+}
+
+// nodeVersionsRoot is where Node.js versions are installed side by side.
+func nodeVersionsRoot() (string, error) {
+	return versionsRoot("node")
+}
+
+// installedNode returns the installed Node.js versions, newest first, and
+// the active one.
+func installedNode() (versions []string, active string, err error) {
+	root, err := nodeVersionsRoot()
+	if err != nil {
+		return nil, "", err
+	}
+	return installedVersions(root, nodeGlobal)
+}
+
+// installNodeVersion installs the Node.js release matching spec next to
+// the other installed versions and makes it the active one.
+func installNodeVersion(ctx context.Context, spec string) error {
+	installed := func(v string) bool {
+		versions, _, err := installedNode()
+		return err == nil && !helpers.IsReinstall() && slices.Contains(versions, v)
+	}
+	if v := strings.TrimPrefix(spec, "v"); installed(v) {
+		helpers.PrintInfo(fmt.Sprintf("Node.js %s is already installed", v))
+		return useNodeVersion(ctx, v)
+	}
+	release, err := resolveNode(ctx, spec)
+	if err != nil {
+		helpers.PrintError("Failed to look up the Node.js release", err)
+		return err
+	}
+	// Aliases such as lts/iron only resolve through the index.
+	if v := strings.TrimPrefix(release.Version, "v"); installed(v) {
+		helpers.PrintInfo(fmt.Sprintf("Node.js %s is already installed", v))
+		return useNodeVersion(ctx, v)
+	}
+	platform, err := nodePlatform()
+	if err != nil {
+		return err
+	}
+	if !release.hasTarball(platform) {
+		return fmt.Errorf("node %s has no tarball for %s", release.Version, platform)
+	}
+	version := strings.TrimPrefix(release.Version, "v")
+	root, err := nodeVersionsRoot()
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("node-%s-%s", release.Version, platform)
+	file := name + ".tar.gz"
+	sum, err := nodeChecksum(ctx, release.Version, file)
+	if err != nil {
+		helpers.PrintError("Failed to look up the Node.js checksum", err)
+		return err
+	}
+	helpers.PrintInfo(fmt.Sprintf("Downloading Node.js %s...", version))
+	archive, cleanup, err := downloadVerified(ctx, nodeDistURL+release.Version+"/"+file, file, sum)
+	if err != nil {
+		helpers.PrintError("Failed to download Node.js", err)
+		return err
+	}
+	defer cleanup()
+
+	helpers.PrintInfo(fmt.Sprintf("Installing Node.js %s into %s...", version, root))
+	if err := unpackVersion(ctx, root, version, archive, name); err != nil {
+		helpers.PrintError("Failed to install Node.js", err)
+		return err
+	}
+	if err := useNodeVersion(ctx, version); err != nil {
+		return err
+	}
+	if lts := release.codename(); lts != "" {
+		version += " (" + lts + ")"
+	}
+	helpers.PrintSuccess(fmt.Sprintf("Node.js %s installed in %s", version, filepath.Join(root, strings.TrimPrefix(release.Version, "v"))))
+	return nil
+}
+
+// useNodeVersion points the current link at an installed version and
+// makes sure it and the shared npm prefix are on PATH.
+func useNodeVersion(ctx context.Context, version string) error {
+	root, err := nodeVersionsRoot()
+	if err != nil {
+		return err
+	}
+	if err := linkCurrent(ctx, root, version); err != nil {
+		return err
+	}
+	if err := runIn(ctx, root, []string{"mkdir", "-p", filepath.Join(root, nodeGlobal)}); err != nil {
+		return err
+	}
+	if err := setNpmPrefix(ctx, root, version); err != nil {
+		return err
+	}
+	return addNodeToPath(root)
+}
+
+// setNpmPrefix points npm's global prefix at the shared directory in the
+// version's own etc/npmrc, npm's global config for that Node.js. Unlike an
+// NPM_CONFIG_PREFIX export, this leaves nvm working.
+func setNpmPrefix(ctx context.Context, root, version string) error {
+	etc := filepath.Join(root, version, "etc")
+	npmrc := filepath.Join(etc, "npmrc")
+	line := "prefix=" + filepath.Join(root, nodeGlobal) + "\n"
+	if data, err := os.ReadFile(npmrc); err == nil && string(data) == line {
+		return nil
+	}
+	if !helpers.NeedsRoot(root) {
+		if err := helpers.MkdirAll(etc, 0755); err != nil {
+			return err
+		}
+		return helpers.WriteFile(npmrc, []byte(line), 0644)
+	}
+	if err := runIn(ctx, root, []string{"mkdir", "-p", etc}); err != nil {
+		return err
+	}
+	if _, err := helpers.Exec(ctx, helpers.Command{Args: []string{"tee", npmrc}, Stdin: strings.NewReader(line), Sudo: true}); err != nil {
+		return fmt.Errorf("failed to write %s: %w", npmrc, err)
+	}
+	return nil
+}
+
+// addNodeToPath puts the active Node.js and the shared npm prefix on
+// PATH, the prefix's bin first so a global package, such as a newer npm,
+// wins over the one bundled with Node.js.
+func addNodeToPath(root string) error {
+	if _, err := helpers.EnsureKettleProfileSourced(); err != nil {
+		return err
+	}
+	added, err := helpers.AddLineToKettleShellProfile(fmt.Sprintf(`export PATH=%s:$PATH`, filepath.Join(root, currentLink, "bin")))
+	if err != nil {
+		return err
+	}
+	if added {
+		helpers.PrintSuccess("Added Node.js to PATH; open a new shell to use it")
+	}
+	global := filepath.Join(root, nodeGlobal)
+	added, err = helpers.AddLineToKettleShellProfile(fmt.Sprintf(`export PATH=%s:$PATH`, filepath.Join(global, "bin")))
+	if err != nil {
+		return err
+	}
+	if added {
+		helpers.PrintSuccess("Added the npm global prefix " + global + " to PATH")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package languages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/internal/paths"
+)
+
+// currentLink is the link to the active version in a versions root.
+const currentLink = "current"
+
+// versionsRoot is where the versions of a language are installed side by
+// side: ~/.local/share/kettle/<name>/<version>, or
+// <prefix>/lib/kettle/<name> with --prefix or --system.
+func versionsRoot(name string) (string, error) {
+	if prefix := helpers.InstallPrefix(); prefix != "" {
+		return filepath.Join(prefix, "lib", "kettle", name), nil
+	}
+	dir, err := paths.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// installedVersions returns the versions installed in root, newest first,
+// and the active one. Other directories, such as a shared prefix for
+// global packages, are named in skip.
+func installedVersions(root string, skip ...string) (versions []string, active string, err error) {
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", root, err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() && !strings.HasPrefix(name, ".") && !slices.Contains(skip, name) {
+			versions = append(versions, name)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return helpers.CompareVersions(versions[i], versions[j]) > 0 })
+	if target, err := os.Readlink(filepath.Join(root, currentLink)); err == nil {
+		active = filepath.Base(target)
+	}
+	return versions, active, nil
+}
+
+// runIn runs the commands that change root, with sudo when it is not
+// writable.
+func runIn(ctx context.Context, root string, commands ...[]string) error {
+	sudo := helpers.NeedsRoot(root)
+	for _, args := range commands {
+		c := helpers.Command{Args: args, Sudo: sudo, Stream: true}
+		if err := helpers.RunCommand(ctx, c); err != nil {
+			return fmt.Errorf("failed to run %s: %w", c, err)
+		}
+	}
+	return nil
+}
+
+// downloadVerified downloads url into the cache as filename and checks
+// it against sha256. The returned function removes the download.
+func downloadVerified(ctx context.Context, url, filename, sha256 string) (string, func(), error) {
+	cacheDir, err := paths.CacheDir()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := helpers.MkdirAll(cacheDir, 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	path := filepath.Join(cacheDir, filename)
+	cleanup := func() {
+		if err := helpers.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			helpers.PrintError("Failed to remove "+path, err)
+		}
+		helpers.UntrackTempFile(path)
+	}
+	if err := helpers.CurrentEffects().Download(ctx, url, path); err != nil {
+		return "", nil, fmt.Errorf("failed to download %s: %w", filename, err)
+	}
+	helpers.TrackTempFile(path)
+	if helpers.IsDryRun() {
+		return path, cleanup, nil
+	}
+	sum, err := helpers.FileSHA256(path)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	if !strings.EqualFold(sum, sha256) {
+		cleanup()
+		return "", nil, fmt.Errorf("%w: %s has sha256 %s, expected %s", helpers.ErrVerification, filename, sum, sha256)
+	}
+	return path, cleanup, nil
+}
+
+// unpackVersion extracts archive, whose files are all under the directory
+// top, into root/version, replacing any earlier install of that version.
+// It unpacks beside the versions and then moves into place, so an
+// interrupted install never leaves a partial version behind.
+func unpackVersion(ctx context.Context, root, version, archive, top string) error {
+	staging := filepath.Join(root, "."+version+".tmp")
+	dest := filepath.Join(root, version)
+	return runIn(ctx, root,
+		[]string{"rm", "-rf", staging},
+		[]string{"mkdir", "-p", staging},
+		[]string{"tar", "-C", staging, "-xf", archive},
+		[]string{"rm", "-rf", dest},
+		[]string{"mv", filepath.Join(staging, top), dest},
+		[]string{"rm", "-rf", staging},
+	)
+}
+
+// linkCurrent makes version, which must be installed, the active version
+// in root.
+func linkCurrent(ctx context.Context, root, version string) error {
+	if _, err := os.Stat(filepath.Join(root, version)); err != nil && !helpers.IsDryRun() {
+		return fmt.Errorf("%s is not installed in %s", version, root)
+	}
+	return runIn(ctx, root, []string{"ln", "-sfn", version, filepath.Join(root, currentLink)})
+}
+
+func orNone(version string) string {
+	if version == "" {
+		return "none"
+	}
+	return version
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listGroup     string
+	listInstalled bool
+)
+
+var tableHeaderStyle = lipgloss.NewStyle().Bold(true).Padding(0, 1)
+var tableCellStyle = lipgloss.NewStyle().Padding(0, 1)
+
+// renderTable renders rows under headers in the style used by kettle's tables.
+func renderTable(headers []string, rows [][]string) string {
+	return table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("8"))).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return tableHeaderStyle
+			}
+			return tableCellStyle
+		}).
+		Headers(headers...).
+		Rows(rows...).
+		String()
+}
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tools kettle can install",
+	Long:  `List every tool kettle knows about, whether it is installed, and the version and path found.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var statuses []registry.Status
+		for _, tool := range registry.All() {
+			if listGroup != "" && tool.Group != listGroup {
+				continue
+			}
+			status := registry.Detect(cmd.Context(), tool)
+			if listInstalled && !status.Installed {
+				continue
+			}
+			statuses = append(statuses, status)
+		}
+
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(statuses)
+			return nil
+		}
+
+		rows := make([][]string, 0, len(statuses))
+		for _, s := range statuses {
+			installed := "no"
+			if s.Installed {
+				installed = "yes"
+			}
+			rows = append(rows, []string{s.Tool, s.Group, installed, s.Version, s.Path})
+		}
+		fmt.Println(renderTable([]string{"Tool", "Group", "Installed", "Version", "Path"}, rows))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringVar(&listGroup, "group", "", "Only list tools in this group (languages, terminal)")
+	listCmd.Flags().BoolVar(&listInstalled, "installed", false, "Only list installed tools")
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/spf13/cobra"
+)
+
+var logLast int
+
+var (
+	runOKStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)
+	runFailedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+	runActionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8")).MarginLeft(4)
+)
+
+// logCmd represents the log command
+var logCmd = &cobra.Command{
+	Use:         "log",
+	Short:       "Browse the log of previous kettle runs",
+	Annotations: map[string]string{noRunLog: ""},
+}
+
+var logShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show recent runs and the changes they made",
+	Long: `Show recent kettle runs from the run log, with each command that was run,
+its exit code and duration, files written or removed, and downloads with
+their SHA-256.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		records, err := helpers.ReadRunLog(logLast)
+		if err != nil {
+			return err
+		}
+
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(records)
+			return nil
+		}
+		if len(records) == 0 {
+			path, _ := helpers.RunLogPath()
+			helpers.PrintInfo(fmt.Sprintf("No runs recorded yet in %s", path))
+			return nil
+		}
+
+		for _, rec := range records {
+			style := runOKStyle
+			if rec.ExitCode != helpers.ExitOK {
+				style = runFailedStyle
+			}
+			header := fmt.Sprintf("%s  %s  exit %d  %s",
+				rec.Time.Local().Format("2006-01-02 15:04:05"),
+				strings.Join(rec.Argv, " "),
+				rec.ExitCode,
+				time.Duration(rec.DurationMS)*time.Millisecond)
+			if rec.DryRun {
+				header += "  (dry run)"
+			}
+			fmt.Println(style.Render(header))
+			if rec.Error != nil {
+				fmt.Println(runActionStyle.Render(fmt.Sprintf("error [%s]: %s", rec.Error.Code, rec.Error.Message)))
+			}
+			for _, a := range rec.Actions {
+				fmt.Println(runActionStyle.Render(formatAction(a)))
+			}
+		}
+		return nil
+	},
+}
+
+func formatAction(a helpers.Action) string {
+	line := fmt.Sprintf("%-8s %s", a.Kind, a.Target)
+	if a.ExitCode != nil {
+		line += fmt.Sprintf("  exit %d", *a.ExitCode)
+	}
+	if a.DurationMS > 0 {
+		line += fmt.Sprintf("  %s", time.Duration(a.DurationMS)*time.Millisecond)
+	}
+	if a.SHA256 != "" {
+		line += "  sha256:" + a.SHA256
+	}
+	if a.Error != "" {
+		line += "  error: " + a.Error
+	}
+	return line
+}
+
+func init() {
+	rootCmd.AddCommand(logCmd)
+	logCmd.AddCommand(logShowCmd)
+
+	logShowCmd.Flags().IntVarP(&logLast, "last", "n", 10, "Number of most recent runs to show (0 for all)")
+}
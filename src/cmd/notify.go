@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/x/term"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/kettleofketchup/kettle/src/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	updateCheckName = "update-check.json"
+	// updateCheckTimeout bounds the lookups of a check.
+	updateCheckTimeout = 10 * time.Second
+	// updateCheckGrace is how long kettle waits for a check that is still
+	// running when the command finishes. A check that misses it is retried
+	// by the next command.
+	updateCheckGrace = time.Second
+)
+
+// updateCheck is the result of looking for newer releases, cached in the
+// state directory so it runs at most once per update_check.interval.
+type updateCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Channel   string    `json:"channel"`
+	// Kettle is the latest kettle release on Channel.
+	Kettle string `json:"kettle,omitempty"`
+	// Tools holds the latest release of each tool kettle installed.
+	Tools map[string]string `json:"tools,omitempty"`
+}
+
+// pendingCheck delivers the check started before the command ran.
+var pendingCheck chan updateCheck
+
+// wantUpdateCheck reports whether cmd should end with an update notice. It
+// never does in CI, without a terminal, for structured output or dry runs,
+// or for help, completion and update itself.
+func wantUpdateCheck(cmd *cobra.Command) bool {
+	if _, on := config.Current().UpdateCheckInterval(); !on {
+		return false
+	}
+	if os.Getenv("CI") != "" || !helpers.IsInteractive() || !term.IsTerminal(os.Stdout.Fd()) {
+		return false
+	}
+	if helpers.IsStructuredOutput() || helpers.IsDryRun() || quiet {
+		return false
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd, "completion", "help", "update":
+			return false
+		}
+	}
+	return cmd.HasParent()
+}
+
+func updateCheckPath() (string, error) {
+	dir, err := helpers.GetKettleStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, updateCheckName), nil
+}
+
+// loadUpdateCheck returns the cached check, or false when there is none.
+func loadUpdateCheck() (updateCheck, bool) {
+	var c updateCheck
+	path, err := updateCheckPath()
+	if err != nil {
+		return c, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, false
+	}
+	return c, json.Unmarshal(data, &c) == nil
+}
+
+// saveUpdateCheck caches c. Like the manifest it is kettle's own
+// bookkeeping, so it is written directly.
+func saveUpdateCheck(c updateCheck) error {
+	path, err := updateCheckPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write update check: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// startUpdateCheck begins looking for newer releases while cmd runs,
+// unless the cached check is recent enough to reuse.
+func startUpdateCheck(cmd *cobra.Command) {
+	if !wantUpdateCheck(cmd) {
+		return
+	}
+	cfg := config.Current()
+	interval, _ := cfg.UpdateCheckInterval()
+	pendingCheck = make(chan updateCheck, 1)
+	if cached, ok := loadUpdateCheck(); ok && cached.Channel == cfg.UpdateChannel && time.Since(cached.CheckedAt) < interval {
+		pendingCheck <- cached
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+		defer cancel()
+		c := runUpdateCheck(ctx, cfg)
+		if err := saveUpdateCheck(c); err != nil {
+			log.Debug("failed to save update check", "err", err)
+		}
+		pendingCheck <- c
+	}()
+}
+
+// runUpdateCheck looks up the latest kettle on the configured channel and
+// the latest release of every unpinned tool in the manifest.
+func runUpdateCheck(ctx context.Context, cfg config.Config) updateCheck {
+	c := updateCheck{CheckedAt: time.Now().UTC(), Channel: cfg.UpdateChannel, Tools: map[string]string{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		releases, err := helpers.GithubListReleases(ctx, kettleOwner, kettleRepo)
+		if err != nil {
+			log.Debug("update check failed", "tool", "kettle", "err", err)
+			return
+		}
+		if r := channelRelease(releases, cfg.UpdateChannel); r != nil {
+			mu.Lock()
+			c.Kettle = r.GetTagName()
+			mu.Unlock()
+		}
+	}()
+
+	manifest, err := registry.LoadManifest()
+	if err != nil {
+		log.Debug("update check skips tools", "err", err)
+	}
+	for name := range manifest.Tools {
+		tool, ok := registry.Lookup(name)
+		if _, pinned := cfg.Pin(name); !ok || pinned || tool.Latest == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(tool registry.Tool) {
+			defer wg.Done()
+			rel, err := tool.Latest(ctx)
+			if err != nil {
+				log.Debug("update check failed", "tool", tool.Name, "err", err)
+				return
+			}
+			mu.Lock()
+			c.Tools[tool.Name] = rel.Version
+			mu.Unlock()
+		}(tool)
+	}
+	wg.Wait()
+	return c
+}
+
+// updateNotice returns the one-line notice for c, or "" when kettle and
+// its tools are up to date. Development builds are never told to update.
+func updateNotice(c updateCheck, manifest registry.Manifest) string {
+	var parts []string
+	if _, ok := helpers.ParseVersion(Version); ok && c.Kettle != "" && helpers.CompareVersions(Version, c.Kettle) < 0 {
+		parts = append(parts, fmt.Sprintf("kettle %s is available (kettle update)", c.Kettle))
+	}
+	var tools []string
+	for name, latest := range c.Tools {
+		if have := manifest.Tools[name].Version; have != "" && helpers.CompareVersions(have, latest) < 0 {
+			tools = append(tools, name)
+		}
+	}
+	if len(tools) > 0 {
+		sort.Strings(tools)
+		parts = append(parts, fmt.Sprintf("newer releases of %s (kettle upgrade --all)", strings.Join(tools, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// printUpdateNotice prints the notice for the check started before the
+// command, waiting briefly if it is still running.
+func printUpdateNotice() {
+	if pendingCheck == nil {
+		return
+	}
+	var c updateCheck
+	select {
+	case c = <-pendingCheck:
+	case <-time.After(updateCheckGrace):
+		return
+	}
+	manifest, err := registry.LoadManifest()
+	if err != nil {
+		return
+	}
+	if notice := updateNotice(c, manifest); notice != "" {
+		helpers.PrintInfo("Update available: " + notice)
+	}
+}
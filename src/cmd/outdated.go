@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/kettleofketchup/kettle/src/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// Outdated statuses.
+const (
+	OutdatedUpToDate = "up_to_date"
+	OutdatedOutdated = "outdated"
+	OutdatedPinned   = "pinned"
+	OutdatedUnknown  = "unknown"
+)
+
+// Outdated compares an installed tool with its latest release.
+type Outdated struct {
+	Tool    string `json:"tool" yaml:"tool"`
+	Current string `json:"current,omitempty" yaml:"current,omitempty"`
+	// Source is "detected" when Current came from the tool itself and
+	// "recorded" when it came from kettle's install manifest.
+	Source    string `json:"source,omitempty" yaml:"source,omitempty"`
+	Latest    string `json:"latest,omitempty" yaml:"latest,omitempty"`
+	Changelog string `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+	Status    string `json:"status" yaml:"status"`
+	// Pin is the version from tools.pinned, if the pin names one.
+	Pin   string `json:"pin,omitempty" yaml:"pin,omitempty"`
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// checkOutdated looks up the latest release of every installed tool in
+// tools. Lookups run concurrently; tools that are not installed are skipped.
+func checkOutdated(ctx context.Context, tools []registry.Tool) []Outdated {
+	manifest, err := registry.LoadManifest()
+	if err != nil {
+		helpers.PrintError("Ignoring install manifest", err)
+	}
+	cfg := config.Current()
+
+	results := make([]*Outdated, len(tools))
+	var wg sync.WaitGroup
+	for i, tool := range tools {
+		st := registry.Detect(ctx, tool)
+		if !st.Installed {
+			continue
+		}
+		o := &Outdated{Tool: tool.Name, Current: st.Version, Source: "detected"}
+		if o.Current == "" {
+			o.Current = manifest.Tools[tool.Name].Version
+			o.Source = "recorded"
+		}
+		if o.Current == "" {
+			o.Source = ""
+		}
+		results[i] = o
+
+		if tool.Latest == nil {
+			o.Status = OutdatedUnknown
+			continue
+		}
+		wg.Add(1)
+		go func(tool registry.Tool, o *Outdated) {
+			defer wg.Done()
+			release, err := tool.Latest(ctx)
+			if err != nil {
+				o.Status = OutdatedUnknown
+				o.Error = err.Error()
+				return
+			}
+			o.Latest = release.Version
+			o.Changelog = release.Changelog
+			switch {
+			case o.Current == "":
+				o.Status = OutdatedUnknown
+			case helpers.CompareVersions(o.Current, o.Latest) < 0:
+				o.Status = OutdatedOutdated
+			default:
+				o.Status = OutdatedUpToDate
+			}
+		}(tool, o)
+	}
+	wg.Wait()
+
+	var out []Outdated
+	for _, o := range results {
+		if o == nil {
+			continue
+		}
+		if pin, pinned := cfg.Pin(o.Tool); pinned {
+			o.Pin = pin
+			// A versioned pin still takes releases within it, so name@1.23
+			// moves from 1.23.1 to 1.23.2 but never to 1.24.
+			if o.Status == OutdatedOutdated && (pin == "" || !helpers.VersionMatches(o.Latest, pin)) {
+				o.Status = OutdatedPinned
+			}
+		}
+		out = append(out, *o)
+	}
+	return out
+}
+
+// outdatedTable renders results as a table of current and latest versions.
+func outdatedTable(results []Outdated) string {
+	rows := make([][]string, 0, len(results))
+	for _, o := range results {
+		status := o.Status
+		if o.Pin != "" {
+			status += " @" + o.Pin
+		}
+		rows = append(rows, []string{o.Tool, o.Current, o.Latest, status, o.Changelog})
+	}
+	return renderTable([]string{"Tool", "Current", "Latest", "Status", "Changelog"}, rows)
+}
+
+// outdatedCmd represents the outdated command
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Show installed tools with newer releases",
+	Long: `Compare the version of each installed tool with its latest release.
+
+The current version is read from the tool itself (for example ` + "`starship --version`" + `),
+falling back to the version kettle recorded when it installed the tool.
+Tools pinned with tools.pinned in the config file are shown as pinned.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		results := checkOutdated(cmd.Context(), registry.All())
+
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(results)
+			return nil
+		}
+		if len(results) == 0 {
+			helpers.PrintInfo("No tools installed.")
+			return nil
+		}
+		fmt.Println(outdatedTable(results))
+		for _, o := range results {
+			if o.Error != "" {
+				helpers.PrintInfo(fmt.Sprintf("Could not check %s: %s", o.Tool, o.Error))
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/kettleofketchup/kettle/src/internal/config"
+)
+
+// pendingTools returns the tools in resolved that need installing. Tools
+// that are already installed are left alone, rather than offered for
+// reinstall, and reported as such, unless they are named in force.
+func pendingTools(ctx context.Context, resolved []registry.Tool, force []string) ([]registry.Tool, []helpers.ToolResult, error) {
+	cfg := config.Current()
+	var todo []registry.Tool
+	var present []helpers.ToolResult
+	for _, tool := range resolved {
+		if !slices.Contains(force, tool.Name) {
+			if st := registry.Detect(ctx, tool); st.Installed {
+				result := helpers.ToolResult{Tool: tool.Name, Status: helpers.StatusAlreadyInstalled, Version: st.Version, Path: st.Path}
+				helpers.ReportResult(result)
+				present = append(present, result)
+				continue
+			}
+		}
+		if !cfg.ToolEnabled(tool.Name) {
+			return nil, nil, fmt.Errorf("%s is disabled by tools.enabled/tools.disabled in the config file", tool.Name)
+		}
+		todo = append(todo, tool)
+	}
+	return todo, present, nil
+}
+
+// installGraph installs tools, given in dependency order as returned by
+// registry.Resolve, running up to jobs installs at once. A tool starts once
+// every dependency in tools has been installed or was already there;
+// dependents of a tool that failed are skipped. Profile edits and sudo
+// prompts are serialised by helpers, and a progress line is shown per tool.
+// It returns the result of every tool, in the order of tools.
+func installGraph(ctx context.Context, tools []registry.Tool, jobs int) ([]helpers.ToolResult, error) {
+	if jobs < 1 || helpers.IsDryRun() {
+		// A dry run plans one tool at a time so the plan reads in order.
+		jobs = 1
+	}
+
+	names := make([]string, len(tools))
+	done := make(map[string]chan struct{}, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+		done[tool.Name] = make(chan struct{})
+	}
+
+	var mu sync.Mutex
+	errs := make(map[string]error, len(tools))
+	errOf := func(name string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return errs[name]
+	}
+	results := make([]helpers.ToolResult, len(tools))
+
+	progress := helpers.NewProgress(names)
+	progress.Start()
+
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, tool := range tools {
+		wg.Add(1)
+		go func(i int, tool registry.Tool) {
+			defer wg.Done()
+			defer close(done[tool.Name])
+
+			err := runNode(ctx, tool, done, errOf, sem, progress)
+
+			mu.Lock()
+			errs[tool.Name] = err
+			mu.Unlock()
+
+			switch {
+			case err == nil:
+				progress.Set(tool.Name, helpers.TaskDone, "installed")
+			case errors.Is(err, helpers.ErrAlreadyInstalled):
+				progress.Set(tool.Name, helpers.TaskDone, "already installed")
+			case errors.Is(err, helpers.ErrDependencyFailed), ctx.Err() != nil:
+				progress.Set(tool.Name, helpers.TaskSkipped, err.Error())
+			default:
+				progress.Set(tool.Name, helpers.TaskFailed, err.Error())
+			}
+			results[i] = recordToolResult(tool, err)
+		}(i, tool)
+	}
+	wg.Wait()
+	progress.Stop()
+
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	var failed []error
+	for _, tool := range tools {
+		if err := errs[tool.Name]; err != nil && !errors.Is(err, helpers.ErrAlreadyInstalled) {
+			failed = append(failed, fmt.Errorf("%s: %w", tool.Name, err))
+		}
+	}
+	return results, errors.Join(failed...)
+}
+
+// runNode waits for the dependencies of tool, then installs it once a
+// worker slot is free.
+func runNode(ctx context.Context, tool registry.Tool, done map[string]chan struct{}, errOf func(string) error, sem chan struct{}, progress *helpers.Progress) error {
+	for _, dep := range tool.Deps {
+		ch, ok := done[dep]
+		if !ok {
+			continue
+		}
+		progress.Set(tool.Name, helpers.TaskWaiting, "waiting for "+dep)
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if depErr := errOf(dep); depErr != nil && !errors.Is(depErr, helpers.ErrAlreadyInstalled) {
+			return fmt.Errorf("%w: %s was not installed", helpers.ErrDependencyFailed, dep)
+		}
+	}
+
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-sem }()
+
+	for _, command := range tool.Requires {
+		if _, ok := helpers.LookupCommand(ctx, command); !ok {
+			return fmt.Errorf("%s needs %s, which kettle does not install", tool.Name, command)
+		}
+	}
+
+	progress.Set(tool.Name, helpers.TaskRunning, "installing")
+	return installTool(ctx, tool)
+}
+
+// printInstallSummary prints a table of what was installed, was already
+// there, was skipped or failed, followed by the counts.
+func printInstallSummary(results []helpers.ToolResult) {
+	if helpers.IsStructuredOutput() || len(results) == 0 {
+		return
+	}
+	counts := map[string]int{}
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		counts[r.Status]++
+		detail := r.Path
+		if r.Error != nil && r.Status != helpers.StatusAlreadyInstalled {
+			detail = r.Error.Message
+		}
+		rows = append(rows, []string{r.Tool, r.Status, r.Version, detail})
+	}
+	fmt.Println(renderTable([]string{"Tool", "Status", "Version", "Path / error"}, rows))
+
+	summary := fmt.Sprintf("%d installed, %d already installed, %d skipped, %d failed",
+		counts[helpers.StatusInstalled]+counts[helpers.StatusPlanned], counts[helpers.StatusAlreadyInstalled],
+		counts[helpers.StatusSkipped]+counts[helpers.StatusDeclined], counts[helpers.StatusFailed])
+	if counts[helpers.StatusFailed] > 0 {
+		helpers.PrintFail(summary)
+		return
+	}
+	helpers.PrintSuccess(summary)
+}
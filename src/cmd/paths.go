@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/internal/config"
+	"github.com/kettleofketchup/kettle/src/internal/paths"
+	"github.com/spf13/cobra"
+)
+
+// pathsCmd represents the paths command
+var pathsCmd = &cobra.Command{
+	Use:   "paths",
+	Short: "Show where kettle keeps its config, cache, state, data and binaries",
+	Long: `Show the directories kettle uses, resolved from XDG_CONFIG_HOME, XDG_CACHE_HOME,
+XDG_STATE_HOME, XDG_DATA_HOME and XDG_BIN_HOME, along with the config file and
+run log inside them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		locs, err := paths.All()
+		if err != nil {
+			return err
+		}
+		if path, err := configPath(); err == nil {
+			loc := paths.Location{Name: "config file", Path: path}
+			if cfgFile == "" && os.Getenv(config.PathEnv) != "" {
+				loc.Env = config.PathEnv
+			}
+			locs = append(locs, loc)
+		}
+		if path, err := helpers.RunLogPath(); err == nil {
+			locs = append(locs, paths.Location{Name: "run log", Path: path})
+		}
+
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(locs)
+			return nil
+		}
+
+		rows := make([][]string, 0, len(locs))
+		for _, loc := range locs {
+			source := "default"
+			if loc.Env != "" {
+				source = "$" + loc.Env
+			}
+			rows = append(rows, []string{loc.Name, loc.Path, source})
+		}
+		fmt.Println(renderTable([]string{"Name", "Path", "Source"}, rows))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pathsCmd)
+}
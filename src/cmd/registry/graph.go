@@ -0,0 +1,54 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Resolve returns the named tools and every tool they depend on, each
+// once, with dependencies before the tools that need them.
+func Resolve(names []string) ([]Tool, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var order []Tool
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), name)
+		}
+		tool, ok := Lookup(name)
+		if !ok {
+			if len(path) > 0 {
+				return fmt.Errorf("%s depends on unknown tool %q", path[len(path)-1], name)
+			}
+			return fmt.Errorf("unknown tool %q (see `kettle list`)", name)
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range tool.Deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, tool)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
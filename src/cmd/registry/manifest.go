@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+)
+
+const manifestName = "tools.json"
+
+// manifestMu serialises updates from tools installing in parallel.
+var manifestMu sync.Mutex
+
+// Installed is what kettle recorded when it installed a tool.
+type Installed struct {
+	Version     string    `json:"version,omitempty" yaml:"version,omitempty"`
+	Path        string    `json:"path,omitempty" yaml:"path,omitempty"`
+	InstalledAt time.Time `json:"installed_at" yaml:"installed_at"`
+}
+
+// Manifest records the tools kettle has installed, keyed by tool name.
+type Manifest struct {
+	Tools map[string]Installed `json:"tools" yaml:"tools"`
+}
+
+// ManifestPath returns the location of the manifest in the state directory.
+func ManifestPath() (string, error) {
+	dir, err := helpers.GetKettleStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, manifestName), nil
+}
+
+// LoadManifest reads the manifest. A missing manifest is empty.
+func LoadManifest() (Manifest, error) {
+	m := Manifest{Tools: map[string]Installed{}}
+	path, err := ManifestPath()
+	if err != nil {
+		return m, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if m.Tools == nil {
+		m.Tools = map[string]Installed{}
+	}
+	return m, nil
+}
+
+// Record stores st as freshly installed. Like the run log, the manifest is
+// kettle's own bookkeeping, so it is written directly and never during a
+// dry run.
+func Record(st Status) error {
+	if helpers.IsDryRun() {
+		return nil
+	}
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	m, err := LoadManifest()
+	if err != nil {
+		return err
+	}
+	m.Tools[st.Tool] = Installed{Version: st.Version, Path: st.Path, InstalledAt: time.Now().UTC()}
+
+	path, err := ManifestPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,192 @@
+// Package registry keeps track of the tools kettle knows how to install.
+// Tool packages register their tools from init.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/spf13/cobra"
+)
+
+// Tool describes an installable tool.
+type Tool struct {
+	Name        string
+	Group       string
+	Description string
+	// Binary is the command that shows the tool is installed. Defaults to Name.
+	Binary string
+	// VersionArgs are passed to Binary to print its version. Defaults to --version.
+	VersionArgs []string
+	// VersionPattern finds the version in the output of VersionArgs. The
+	// first group is used when it has one. Defaults to the first x.y[.z].
+	VersionPattern *regexp.Regexp
+	// Latest looks up the newest release, for `kettle outdated`. Tools
+	// without it are never reported as outdated.
+	Latest func(ctx context.Context) (Release, error)
+	// Check overrides the command lookup for tools that are not commands.
+	Check func() (path string, ok bool)
+	// Deps names the registered tools that must be installed first.
+	Deps []string
+	// Requires lists commands the install needs that kettle does not
+	// install itself, such as git.
+	Requires []string
+	// InstallVersion installs a specific version, for `kettle use` when the
+	// version is not kept yet. Tools without it can only switch between
+	// versions kettle has kept.
+	InstallVersion func(ctx context.Context, version string) error
+	// Versions lists the installed versions, newest first, and the active
+	// one, for tools such as go that keep their versions side by side
+	// themselves. Without it, rollback and use switch between the
+	// versions kettle keeps of a single binary.
+	Versions func() (versions []string, active string, err error)
+	// UseVersion makes an installed version active. It is set along with
+	// Versions.
+	UseVersion func(ctx context.Context, version string) error
+	// Installer installs the tool with its defaults. It is what kettle
+	// install, sets and apply run, safely from several goroutines at once.
+	Installer func(ctx context.Context) error
+	// Install is the command that installs the tool. It runs Installer,
+	// or a variant of it driven by flags and arguments.
+	Install *cobra.Command
+}
+
+// Release is a published version of a tool.
+type Release struct {
+	Version string `json:"version" yaml:"version"`
+	// Changelog links to the release notes.
+	Changelog string `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+}
+
+// GitHubLatest returns a Latest func for tools released on GitHub.
+func GitHubLatest(owner, repo string) func(ctx context.Context) (Release, error) {
+	return func(ctx context.Context) (Release, error) {
+		release, err := helpers.GithubGetLatestRelease(ctx, owner, repo)
+		if err != nil {
+			return Release{}, err
+		}
+		return Release{
+			Version:   strings.TrimPrefix(release.GetTagName(), "v"),
+			Changelog: release.GetHTMLURL(),
+		}, nil
+	}
+}
+
+// Status is what kettle found on the machine for a tool.
+type Status struct {
+	Tool      string `json:"tool" yaml:"tool"`
+	Group     string `json:"group" yaml:"group"`
+	Installed bool   `json:"installed" yaml:"installed"`
+	Version   string `json:"version,omitempty" yaml:"version,omitempty"`
+	Path      string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+var (
+	mu    sync.RWMutex
+	tools = map[string]Tool{}
+)
+
+// Register adds t to the registry. Registering the same name twice panics.
+func Register(t Tool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := tools[t.Name]; ok {
+		panic(fmt.Sprintf("registry: tool %q registered twice", t.Name))
+	}
+	if t.Binary == "" {
+		t.Binary = t.Name
+	}
+	if t.VersionArgs == nil {
+		t.VersionArgs = []string{"--version"}
+	}
+	tools[t.Name] = t
+}
+
+// All returns every registered tool sorted by name.
+func All() []Tool {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make([]Tool, 0, len(tools))
+	for _, t := range tools {
+		all = append(all, t)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Lookup returns the tool registered under name.
+func Lookup(name string) (Tool, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	t, ok := tools[name]
+	return t, ok
+}
+
+// ForCommand returns the tool installed by cmd, if any.
+func ForCommand(cmd *cobra.Command) (Tool, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, t := range tools {
+		if t.Install == cmd {
+			return t, true
+		}
+	}
+	return Tool{}, false
+}
+
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?([-+][0-9A-Za-z.-]+)?`)
+
+// ExtractVersion returns the first version number found in output.
+func ExtractVersion(output string) string {
+	return versionPattern.FindString(output)
+}
+
+// version returns the version of t in the output of its version command.
+func (t Tool) version(output string) string {
+	if t.VersionPattern == nil {
+		return ExtractVersion(output)
+	}
+	m := t.VersionPattern.FindStringSubmatch(output)
+	switch {
+	case m == nil:
+		return ""
+	case len(m) > 1:
+		return m[1]
+	default:
+		return m[0]
+	}
+}
+
+// Detect looks for t on the machine without changing anything.
+func Detect(ctx context.Context, t Tool) Status {
+	st := Status{Tool: t.Name, Group: t.Group}
+	if t.Check != nil {
+		st.Path, st.Installed = t.Check()
+		return st
+	}
+
+	path, ok := helpers.LookupCommand(ctx, t.Binary)
+	if !ok {
+		return st
+	}
+	st.Installed = true
+	st.Path = path
+
+	args := append([]string{t.Binary}, t.VersionArgs...)
+	var res helpers.Result
+	var err error
+	if path != "" {
+		res, err = helpers.Probe(ctx, args...)
+	} else {
+		res, err = helpers.ProbeWithShellProfile(ctx, args...)
+	}
+	if err == nil {
+		st.Version = t.version(res.Stdout + res.Stderr)
+	}
+	return st
+}
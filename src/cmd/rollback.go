@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/spf13/cobra"
+)
+
+// Switch is the result of `kettle rollback` and `kettle use`.
+type Switch struct {
+	Tool     string   `json:"tool" yaml:"tool"`
+	From     string   `json:"from,omitempty" yaml:"from,omitempty"`
+	To       string   `json:"to" yaml:"to"`
+	Path     string   `json:"path" yaml:"path"`
+	Versions []string `json:"versions,omitempty" yaml:"versions,omitempty"`
+}
+
+// toolNames completes the names of registered tools.
+func toolNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	for _, tool := range registry.All() {
+		names = append(names, tool.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func lookupTool(name string) (registry.Tool, error) {
+	tool, ok := registry.Lookup(name)
+	if !ok {
+		return registry.Tool{}, fmt.Errorf("unknown tool %q (see `kettle list`)", name)
+	}
+	return tool, nil
+}
+
+// toolVersions returns the versions of tool kettle can switch between,
+// newest first, and the active one: those its own version manager
+// installed, or else the kept versions of its binary.
+func toolVersions(tool registry.Tool) (versions []string, active string, err error) {
+	if tool.Versions != nil {
+		return tool.Versions()
+	}
+	versions, err = helpers.KeptVersions(tool.Name)
+	if err != nil {
+		return nil, "", err
+	}
+	return versions, helpers.ActiveVersion(tool.Name, tool.Binary), nil
+}
+
+// switchVersion makes version of tool the active one and reports it.
+func switchVersion(ctx context.Context, tool registry.Tool, from, version string) error {
+	var path string
+	if tool.UseVersion != nil {
+		if err := tool.UseVersion(ctx, version); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if path, err = helpers.ActivateVersion(tool.Name, version, tool.Binary); err != nil {
+			return err
+		}
+	}
+	status := registry.Detect(ctx, tool)
+	if err := registry.Record(status); err != nil {
+		log.Warn("failed to record install", "tool", tool.Name, "err", err)
+	}
+	if path == "" {
+		path = status.Path
+	}
+	versions, _, _ := toolVersions(tool)
+	helpers.SetReportData(Switch{Tool: tool.Name, From: from, To: version, Path: path, Versions: versions})
+	helpers.PrintSuccess(fmt.Sprintf("%s is now %s", tool.Name, version))
+	return nil
+}
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <tool>",
+	Short: "Switch a tool back to the version installed before the current one",
+	Long: fmt.Sprintf(`Switch a tool back to the newest kept version older than the active one.
+
+kettle keeps the last %d versions of the tools it installs as single
+binaries (such as golangci-lint) in its data directory, and links the
+active one into the install directory. Go and Node.js switch between the
+versions installed side by side by kettle languages go and node.`, helpers.KeepVersions),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: toolNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tool, err := lookupTool(args[0])
+		if err != nil {
+			return err
+		}
+		versions, active, err := toolVersions(tool)
+		if err != nil {
+			return err
+		}
+		if active == "" {
+			return fmt.Errorf("%s is not managed by kettle; reinstall it with kettle to keep versions", tool.Name)
+		}
+		for _, v := range versions {
+			if helpers.CompareVersions(v, active) < 0 {
+				helpers.PrintInfo(fmt.Sprintf("Rolling %s back from %s to %s", tool.Name, active, v))
+				return switchVersion(cmd.Context(), tool, active, v)
+			}
+		}
+		return fmt.Errorf("no version of %s older than %s is kept (kept: %s)", tool.Name, active, strings.Join(versions, ", "))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+}
@@ -6,14 +6,23 @@ Copyright © 2025 kettleofketchup
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/log"
 
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
 	"github.com/kettleofketchup/kettle/src/cmd/languages"
 	"github.com/kettleofketchup/kettle/src/cmd/platforms/linux"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
 	"github.com/kettleofketchup/kettle/src/cmd/sets"
 	"github.com/kettleofketchup/kettle/src/cmd/tools"
+	"github.com/kettleofketchup/kettle/src/internal/config"
 
 	"github.com/spf13/cobra"
 )
@@ -31,14 +40,243 @@ to quickly create a Cobra application.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+
+	// Execute reports errors itself so it can pick the exit code.
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Flags parsed fine, so later errors are not usage mistakes.
+		cmd.SilenceUsage = true
+
+		format, err := helpers.ParseOutputFormat(output)
+		if err != nil {
+			return err
+		}
+		helpers.SetOutputFormat(format)
+		if err := setupLogging(); err != nil {
+			return err
+		}
+
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if prefix != "" {
+			if !filepath.IsAbs(prefix) {
+				return fmt.Errorf("--prefix must be an absolute path, got %q", prefix)
+			}
+			cfg.InstallPrefix = prefix
+			config.SetCurrent(cfg)
+		}
+		helpers.SetSystemInstall(system)
+
+		helpers.SetDryRun(dryRun)
+		helpers.SetNoSudo(noSudo)
+		helpers.SetUseAskpass(askpass)
+		switch {
+		case assumeYes:
+			helpers.SetPromptMode(helpers.PromptYes)
+		case assumeNo:
+			helpers.SetPromptMode(helpers.PromptNo)
+		case cfg.Prompts.Default == config.PromptYes:
+			helpers.SetPromptMode(helpers.PromptYes)
+		case cfg.Prompts.Default == config.PromptNo:
+			helpers.SetPromptMode(helpers.PromptNo)
+		}
+
+		if tool, ok := registry.ForCommand(cmd); ok && !cfg.ToolEnabled(tool.Name) {
+			return fmt.Errorf("%s is disabled by tools.enabled/tools.disabled in the config file", tool.Name)
+		}
+		helpers.SetTimeouts(helpers.Timeouts{Download: downloadTimeout, Command: commandTimeout})
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cancelTimeout = cancel
+			cmd.SetContext(ctx)
+			helpers.SetContext(ctx)
+		}
+		startUpdateCheck(cmd)
+		return nil
+	},
 }
 
+var (
+	// verbose, quiet, logLevel and logStderr control logging and how much
+	// is printed.
+	verbose   bool
+	quiet     bool
+	logLevel  string
+	logStderr bool
+
+	// dryRun records side effects as a plan instead of executing them.
+	dryRun bool
+
+	// noSudo fails fast instead of asking for root; askpass prompts for
+	// the sudo password with kettle's own helper via SUDO_ASKPASS.
+	noSudo  bool
+	askpass bool
+
+	// prefix installs tools under <prefix>; system installs for all users.
+	prefix string
+	system bool
+
+	// cfgFile overrides the location of the config file.
+	cfgFile string
+
+	// output selects styled text or a JSON/YAML report on stdout.
+	output string
+
+	// assumeYes and assumeNo answer every prompt without asking.
+	assumeYes bool
+	assumeNo  bool
+
+	timeout         time.Duration
+	downloadTimeout time.Duration
+	commandTimeout  time.Duration
+	cancelTimeout   context.CancelFunc = func() {}
+)
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Ctrl-C cancels the root context, which stops in-flight downloads and
+// subprocesses; partial files are removed before exiting.
 func Execute() {
-	err := rootCmd.Execute()
+	// sudo runs kettle itself as the askpass helper when --askpass is set.
+	if os.Getenv(helpers.AskpassEnv) == "1" {
+		if err := helpers.RunAskpass(os.Args[1:]); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	start := time.Now()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	helpers.SetContext(ctx)
+
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	interrupted := ctx.Err() != nil
+	cancelTimeout()
+	stop()
+
+	helpers.CleanupTempFiles()
+	code := helpers.ExitCode(err)
+	if interrupted {
+		code = helpers.ExitInterrupted
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+
+	reportToolResult(cmd, err)
+	if shouldLogRun(cmd) {
+		logRun(cmd, start, code, err)
+	}
+
+	if helpers.IsStructuredOutput() {
+		if werr := helpers.WriteReport(os.Stdout, cmd.CommandPath(), code, err); werr != nil {
+			fmt.Fprintln(os.Stderr, "Error:", werr)
+		}
+		os.Exit(code)
+	}
+
+	helpers.PrintPlan()
+	switch code {
+	case helpers.ExitOK:
+		printUpdateNotice()
+	case helpers.ExitInterrupted:
+		helpers.PrintFail("Interrupted")
+		os.Exit(code)
+	case helpers.ExitFailed:
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(code)
+	default:
+		helpers.PrintInfo(err.Error())
+		os.Exit(code)
+	}
+}
+
+// reportToolResult adds the outcome to the report when cmd installs a
+// registered tool.
+func reportToolResult(cmd *cobra.Command, err error) {
+	if tool, ok := registry.ForCommand(cmd); ok {
+		recordToolResult(tool, err)
+	}
+}
+
+// recordToolResult adds the outcome of installing tool to the report, along
+// with the version and path found afterwards, and records successful
+// installs in the manifest. It returns the result it reported.
+func recordToolResult(tool registry.Tool, err error) helpers.ToolResult {
+	status := registry.Detect(context.Background(), tool)
+	if err == nil && status.Installed {
+		if rerr := registry.Record(status); rerr != nil {
+			log.Warn("failed to record install", "tool", tool.Name, "err", rerr)
+		}
+	}
+	result := helpers.ToolResult{
+		Tool:    tool.Name,
+		Status:  helpers.ResultStatus(err),
+		Version: status.Version,
+		Path:    status.Path,
+		Error:   helpers.NewErrorInfo(err),
+	}
+	helpers.ReportResult(result)
+	return result
+}
+
+// setupLogging applies --verbose, --quiet, --log-level and --log-stderr.
+func setupLogging() error {
+	level, err := log.ParseLevel(logLevel)
 	if err != nil {
-		os.Exit(1)
+		return fmt.Errorf("invalid log level %q: %w", logLevel, err)
+	}
+	if verbose {
+		level = log.DebugLevel
+	}
+	log.SetLevel(level)
+	log.SetOutput(os.Stderr)
+
+	helpers.SetQuiet(quiet)
+	if logStderr {
+		helpers.SetOutput(os.Stderr)
+	}
+	return nil
+}
+
+// noRunLog marks commands, such as `kettle log`, whose runs are not
+// worth recording in the run log.
+const noRunLog = "kettle.no-run-log"
+
+func shouldLogRun(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if _, skip := c.Annotations[noRunLog]; skip {
+			return false
+		}
+		switch c.Name() {
+		case cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd, "completion", "help":
+			return false
+		}
+	}
+	return cmd != rootCmd
+}
+
+// logRun appends this run to the run log. A log that cannot be written
+// never fails the command.
+func logRun(cmd *cobra.Command, start time.Time, code int, err error) {
+	dir, _ := os.Getwd()
+	rec := helpers.RunRecord{
+		Time:       start,
+		Argv:       os.Args,
+		Command:    cmd.CommandPath(),
+		Version:    Version,
+		Dir:        dir,
+		DryRun:     helpers.IsDryRun(),
+		ExitCode:   code,
+		DurationMS: time.Since(start).Milliseconds(),
+		Error:      helpers.NewErrorInfo(err),
+		Results:    helpers.Results(),
+		Actions:    helpers.Actions(),
+	}
+	if werr := helpers.AppendRunLog(rec); werr != nil {
+		log.Warn("failed to write run log", "err", werr)
 	}
 }
 
@@ -52,15 +290,25 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kettle.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", string(helpers.OutputText), "Output format: text, json or yaml")
+	rootCmd.PersistentFlags().StringVar(&prefix, "prefix", "", "Install tools under this prefix, e.g. /opt/kettle (binaries go to <prefix>/bin)")
+	rootCmd.PersistentFlags().BoolVar(&system, "system", false, "Install for all users under /usr/local (or --prefix) with profile snippets in /etc/profile.d and /etc/fish/conf.d")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the commands, file changes and downloads that would run without executing them")
+	rootCmd.PersistentFlags().BoolVar(&noSudo, "no-sudo", false, "Never use sudo; fail fast or install user-locally when root is required")
+	rootCmd.PersistentFlags().BoolVar(&askpass, "askpass", false, "Prompt for the sudo password with kettle's askpass helper instead of sudo's own prompt")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to every prompt")
+	rootCmd.PersistentFlags().BoolVar(&assumeNo, "no", false, "Answer no to every prompt")
+	rootCmd.MarkFlagsMutuallyExclusive("yes", "no")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Abort the whole command after this long (0 for no limit)")
+	rootCmd.PersistentFlags().DurationVar(&downloadTimeout, "download-timeout", 10*time.Minute, "Abort a single download after this long (0 for no limit)")
+	rootCmd.PersistentFlags().DurationVar(&commandTimeout, "command-timeout", 0, "Abort a single subprocess after this long (0 for no limit)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file (default $KETTLE_CONFIG or ~/.config/kettle/config.yaml)")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable debug logging (same as --log-level debug)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Hide info messages and command output; only successes and errors are printed")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "error", "Log level: debug, info, warn, error or fatal")
+	rootCmd.PersistentFlags().BoolVar(&logStderr, "log-stderr", false, "Print messages and command output to stderr so stdout can be piped")
 
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	rootCmd.Flags().BoolP("verbose", "v", false, "Help message for verbose")
-	if verbose, _ := rootCmd.Flags().GetBool("verbose"); verbose {
-		log.SetLevel(log.DebugLevel)
-	} else {
-		log.SetLevel(log.ErrorLevel)
-	}
+	// Until flags are parsed only errors are logged.
+	log.SetLevel(log.ErrorLevel)
 }
@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/internal/config"
+)
+
+// kettleAsset is the plain linux/amd64 binary of releases made before
+// there were assets per platform.
+const kettleAsset = "kettle"
+
+// checksumAssets are the release files that may hold the checksum of the
+// binary, in the order they are tried. %s is the binary's asset name.
+var checksumAssets = []string{"%s.sha256", "checksums.txt", "SHA256SUMS"}
+
+// skipVerify installs a release even when it cannot be verified.
+var skipVerify bool
+
+// kettleExecutable returns the path of the running kettle, with symlinks
+// resolved so the binary itself is replaced rather than a link to it.
+func kettleExecutable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current executable path: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return exe, nil
+}
+
+// kettleBinary is the name of the kettle executable in release archives.
+func kettleBinary() string {
+	if runtime.GOOS == "windows" {
+		return "kettle.exe"
+	}
+	return "kettle"
+}
+
+// selfUpdateAsset picks the release asset for this platform:
+// kettle_<os>_<arch>.tar.gz as built by make release, or a bare
+// kettle_<os>_<arch> binary. The plain kettle binary of older releases is
+// the fallback; its header is checked before it is installed. Signatures
+// and checksum files never match.
+func selfUpdateAsset(release *github.RepositoryRelease) (*github.ReleaseAsset, error) {
+	base := fmt.Sprintf("kettle_%s_%s", runtime.GOOS, runtime.GOARCH)
+	for _, name := range []string{base + ".tar.gz", base + ".zip", base, base + ".exe", kettleAsset} {
+		if a := releaseAsset(release, name); a != nil {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("release %s has no kettle for %s/%s", release.GetTagName(), runtime.GOOS, runtime.GOARCH)
+}
+
+// backupPath is where the binary an update replaced is kept.
+func backupPath(exe string) string {
+	return exe + ".old"
+}
+
+// stagingDir returns a new, unique directory next to exe to download and
+// unpack a release in.
+func stagingDir(exe string) (string, error) {
+	dir := filepath.Dir(exe)
+	if helpers.IsDryRun() {
+		return filepath.Join(dir, ".kettle-update.d"), nil
+	}
+	tmp, err := os.MkdirTemp(dir, ".kettle-update-*.d")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a directory in %s: %w", dir, err)
+	}
+	return tmp, nil
+}
+
+// stagingFile returns a new, unique file next to exe, so concurrent
+// updates do not collide and the final rename never crosses filesystems.
+func stagingFile(exe string) (string, error) {
+	dir := filepath.Dir(exe)
+	if helpers.IsDryRun() {
+		return filepath.Join(dir, ".kettle-update"), nil
+	}
+	f, err := os.CreateTemp(dir, ".kettle-update-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a file in %s: %w", dir, err)
+	}
+	name := f.Name()
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	helpers.TrackTempFile(name)
+	return name, nil
+}
+
+// discard removes a staged file that will not be installed.
+func discard(path string) {
+	if err := helpers.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		helpers.PrintError("Failed to remove temporary file", err)
+	}
+	helpers.UntrackTempFile(path)
+}
+
+func releaseAsset(release *github.RepositoryRelease, name string) *github.ReleaseAsset {
+	for i := range release.Assets {
+		if release.Assets[i].GetName() == name {
+			return &release.Assets[i]
+		}
+	}
+	return nil
+}
+
+// fetchAsset downloads a small release file, such as a checksum list,
+// into a temporary file next to exe and returns its content.
+func fetchAsset(ctx context.Context, exe string, asset *github.ReleaseAsset) ([]byte, error) {
+	tmp, err := stagingFile(exe)
+	if err != nil {
+		return nil, err
+	}
+	defer discard(tmp)
+	if err := helpers.CurrentEffects().Download(ctx, asset.GetBrowserDownloadURL(), tmp); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.GetName(), err)
+	}
+	return os.ReadFile(tmp)
+}
+
+// releaseChecksum returns the SHA-256 the release publishes for asset,
+// from either a <asset>.sha256 file or a checksum list.
+func releaseChecksum(ctx context.Context, exe string, release *github.RepositoryRelease, asset string) (string, error) {
+	for _, pattern := range checksumAssets {
+		name := pattern
+		if strings.Contains(pattern, "%s") {
+			name = fmt.Sprintf(pattern, asset)
+		}
+		a := releaseAsset(release, name)
+		if a == nil {
+			continue
+		}
+		data, err := fetchAsset(ctx, exe, a)
+		if err != nil {
+			return "", err
+		}
+		if sum := findChecksum(data, asset); sum != "" {
+			return sum, nil
+		}
+		return "", fmt.Errorf("%w: %s has no checksum for %s", helpers.ErrVerification, name, asset)
+	}
+	return "", fmt.Errorf("%w: release %s publishes no checksum for %s (use --no-verify to install it anyway)",
+		helpers.ErrVerification, release.GetTagName(), asset)
+}
+
+// findChecksum reads sha256sum output: "<sum>  <name>" lines, or a single
+// bare sum.
+func findChecksum(data []byte, asset string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 1:
+			return fields[0]
+		case len(fields) >= 2 && strings.TrimPrefix(fields[1], "*") == asset:
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// verifyRelease checks the staged binary against the release's checksum.
+func verifyRelease(ctx context.Context, exe string, release *github.RepositoryRelease, asset, staged string) error {
+	if helpers.IsDryRun() {
+		return nil
+	}
+	if skipVerify {
+		helpers.PrintFail("Skipping verification of the downloaded binary (--no-verify)")
+		return nil
+	}
+	want, err := releaseChecksum(ctx, exe, release, asset)
+	if err != nil {
+		return err
+	}
+	got, err := helpers.FileSHA256(staged)
+	if err != nil {
+		return fmt.Errorf("failed to hash download: %w", err)
+	}
+	if !strings.EqualFold(got, want) {
+		return fmt.Errorf("%w: %s has sha256 %s, release says %s", helpers.ErrVerification, asset, got, want)
+	}
+	return nil
+}
+
+// smokeTest runs `<binary> version` and returns the version it reports.
+// When want is a release version the reported version must be exactly
+// it, so a broken or mislabelled build is never installed. The binary
+// runs non-interactively with a scratch state directory and no config
+// file, so it neither checks for updates nor writes to the run log.
+func smokeTest(ctx context.Context, binary, want string) (string, error) {
+	if helpers.IsDryRun() {
+		return "", nil
+	}
+	scratch, err := os.MkdirTemp("", "kettle-smoke-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+	res, err := helpers.Exec(ctx, helpers.Command{
+		Args: []string{binary, "version"},
+		Env: []string{
+			helpers.NonInteractiveEnv + "=1",
+			"CI=1",
+			config.PathEnv + "=" + filepath.Join(scratch, config.FileName),
+			"XDG_STATE_HOME=" + scratch,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s does not run: %w", binary, err)
+	}
+	got := reportedVersion(res.Stdout + "\n" + res.Stderr)
+	if _, ok := helpers.ParseVersion(want); ok && helpers.CompareVersions(got, want) != 0 {
+		return "", fmt.Errorf("%w: %s reports version %q, expected %s", helpers.ErrVerification, binary, got, want)
+	}
+	return got, nil
+}
+
+// reportedVersion finds the version in `kettle version` output, which
+// reads "kettle <version> (commit: ..., built: ...)".
+func reportedVersion(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if _, rest, ok := strings.Cut(line, "kettle "); ok {
+			if fields := strings.Fields(rest); len(fields) > 0 {
+				return fields[0]
+			}
+		}
+	}
+	return ""
+}
+
+// swapBinary keeps a copy of exe as its backup and puts staged in its
+// place.
+func swapBinary(exe, staged string) error {
+	current, err := os.ReadFile(exe)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", exe, err)
+	}
+	if err := helpers.WriteFile(backupPath(exe), current, 0755); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", exe, err)
+	}
+	// WriteFile keeps the mode of an existing backup.
+	if err := helpers.Chmod(backupPath(exe), 0755); err != nil {
+		return err
+	}
+	if err := atomicReplace(exe, staged); err != nil {
+		return err
+	}
+	helpers.UntrackTempFile(staged)
+	return nil
+}
+
+// installRelease downloads the kettle asset of release for this platform
+// next to the running binary, verifies, unpacks and smoke tests it, and
+// swaps it in, keeping the old binary.
+func installRelease(ctx context.Context, release *github.RepositoryRelease, targetVersion string) error {
+	exe, err := kettleExecutable()
+	if err != nil {
+		return err
+	}
+	asset, err := selfUpdateAsset(release)
+	if err != nil {
+		return err
+	}
+	dir, err := stagingDir(exe)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := helpers.RemoveAll(dir); err != nil {
+			helpers.PrintError("Failed to remove temporary directory", err)
+		}
+	}()
+
+	download := filepath.Join(dir, asset.GetName())
+	if err := helpers.CurrentEffects().Download(ctx, asset.GetBrowserDownloadURL(), download); err != nil {
+		return fmt.Errorf("failed to download %s: %w", asset.GetName(), err)
+	}
+	helpers.PrintInfo("Verifying download...")
+	if err := verifyRelease(ctx, exe, release, asset.GetName(), download); err != nil {
+		return err
+	}
+	if err := helpers.ExtractBinaryFromArchive(ctx, download, dir, kettleBinary()); err != nil {
+		return fmt.Errorf("failed to extract kettle from %s: %w", asset.GetName(), err)
+	}
+	binary := filepath.Join(dir, kettleBinary())
+	if !helpers.IsDryRun() {
+		if err := helpers.CheckBinaryPlatform(binary, runtime.GOOS, runtime.GOARCH); err != nil {
+			return err
+		}
+	}
+	if _, err := smokeTest(ctx, binary, targetVersion); err != nil {
+		return err
+	}
+
+	// The binary leaves the staging directory, which is removed on return,
+	// because Windows only swaps it in after kettle exits.
+	staged, err := stagingFile(exe)
+	if err != nil {
+		return err
+	}
+	if err := helpers.Rename(binary, staged); err != nil {
+		discard(staged)
+		return fmt.Errorf("failed to stage %s: %w", binary, err)
+	}
+	helpers.PrintInfo("Replacing current binary...")
+	if err := swapBinary(exe, staged); err != nil {
+		discard(staged)
+		return err
+	}
+	return nil
+}
+
+// rollbackKettle puts back the binary the last update replaced. The
+// replaced binary becomes the backup, so a second rollback undoes the
+// first.
+func rollbackKettle(ctx context.Context) error {
+	exe, err := kettleExecutable()
+	if err != nil {
+		return err
+	}
+	old := backupPath(exe)
+	data, err := os.ReadFile(old)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no previous version to roll back to: %s does not exist", old)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", old, err)
+	}
+	previous, err := smokeTest(ctx, old, "")
+	if err != nil {
+		return err
+	}
+	if previous == "" {
+		previous = old
+	}
+	if !helpers.PromptYesNo(fmt.Sprintf("Roll back kettle from v%s to %s?", strings.TrimPrefix(Version, "v"), previous), true) {
+		return helpers.ErrDeclined
+	}
+
+	staged, err := stagingFile(exe)
+	if err != nil {
+		return err
+	}
+	if err := helpers.WriteFile(staged, data, 0755); err != nil {
+		discard(staged)
+		return fmt.Errorf("failed to stage %s: %w", old, err)
+	}
+	if err := helpers.Chmod(staged, 0755); err != nil {
+		discard(staged)
+		return err
+	}
+	if err := swapBinary(exe, staged); err != nil {
+		discard(staged)
+		return err
+	}
+	helpers.PrintSuccess(fmt.Sprintf("Rolled back to %s; run kettle update --rollback again to undo", previous))
+	return nil
+}
@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/kettleofketchup/kettle/src/cmd/sets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setsInstallJobs int
+	setsAddName     string
+	setsAddSHA256   string
+	setsAddKey      string
+	setsSyncOnly    bool
+)
+
+func lookupSet(name string) (sets.Set, error) {
+	set, ok := sets.Lookup(name)
+	if !ok {
+		return sets.Set{}, fmt.Errorf("unknown set %q (see `kettle sets list`)", name)
+	}
+	return set, nil
+}
+
+var setsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the built-in and configured tool sets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all := sets.All()
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(all)
+			return nil
+		}
+		rows := make([][]string, 0, len(all))
+		for _, s := range all {
+			rows = append(rows, []string{s.Name, s.Source, strings.Join(s.Tools, ", "), s.Description})
+		}
+		fmt.Println(renderTable([]string{"Set", "Source", "Tools", "Description"}, rows))
+		return nil
+	},
+}
+
+var setsShowCmd = &cobra.Command{
+	Use:               "show <set>",
+	Short:             "Show the tools in a set, their dependencies and what is installed",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: sets.Names,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		set, err := lookupSet(args[0])
+		if err != nil {
+			return err
+		}
+		resolved, err := registry.Resolve(set.Tools)
+		if err != nil {
+			return fmt.Errorf("set %s: %w", set.Name, err)
+		}
+		statuses := make([]registry.Status, 0, len(resolved))
+		for _, tool := range resolved {
+			statuses = append(statuses, registry.Detect(cmd.Context(), tool))
+		}
+
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(struct {
+				sets.Set `yaml:",inline"`
+				Status   []registry.Status `json:"status" yaml:"status"`
+			}{set, statuses})
+			return nil
+		}
+		if set.Description != "" {
+			helpers.PrintInfo(fmt.Sprintf("%s: %s", set.Name, set.Description))
+		}
+		rows := make([][]string, 0, len(statuses))
+		for i, st := range statuses {
+			role := "member"
+			if !slices.Contains(set.Tools, st.Tool) {
+				role = "dependency"
+			}
+			rows = append(rows, []string{st.Tool, role, strings.Join(resolved[i].Deps, ", "), yesNo(st.Installed), st.Version})
+		}
+		fmt.Println(renderTable([]string{"Tool", "Role", "Needs", "Installed", "Version"}, rows))
+		return nil
+	},
+}
+
+var setsInstallCmd = &cobra.Command{
+	Use:   "install <set>",
+	Short: "Install every tool in a set, dependencies first",
+	Long: `Install the tools of a set and their dependencies in dependency order,
+independent tools in parallel. Tools that are already installed are left
+alone. A summary table shows what was installed, skipped or failed.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: sets.Names,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		set, err := lookupSet(args[0])
+		if err != nil {
+			return err
+		}
+		resolved, err := registry.Resolve(set.Tools)
+		if err != nil {
+			return fmt.Errorf("set %s: %w", set.Name, err)
+		}
+		todo, present, err := pendingTools(cmd.Context(), resolved, nil)
+		if err != nil {
+			return err
+		}
+		if len(todo) > 0 {
+			helpers.PrintInfo(fmt.Sprintf("Installing set %s: %s", set.Name, strings.Join(toolNamesOf(todo), ", ")))
+		}
+		results, err := installGraph(cmd.Context(), todo, setsInstallJobs)
+		printInstallSummary(append(present, results...))
+		return err
+	},
+}
+
+var setsAddCmd = &cobra.Command{
+	Use:   "add <url>",
+	Short: "Subscribe to set definitions published at a URL or in a git repository",
+	Long: `Subscribe to remote set definitions, written like the sets: section of the
+config file:
+
+  kettle sets add https://example.com/team.yaml
+  kettle sets add git+https://example.com/dotfiles.git#kettle/
+
+A git URL is cloned and the fragment names the file, or a directory holding
+sets.yaml. The definitions are cached and verified on every fetch:
+
+  --sha256   pins the exact content; it only changes when added again or
+             accepted by sync
+  --pubkey   requires a valid ed25519 signature in <file>.sig, so the team
+             can publish updates
+
+Without either, the checksum of the first fetch is pinned. Once the source
+changes, sync shows the new checksum and asks whether to accept it; the
+answer defaults to no, which keeps the cached definitions.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rawURL := args[0]
+		if !strings.HasPrefix(rawURL, "https://") && !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "git+") {
+			return fmt.Errorf("unsupported URL %q: use https:// or git+<repository>#<path>", rawURL)
+		}
+		sources, err := sets.LoadSources()
+		if err != nil {
+			return err
+		}
+		src := sets.Source{Name: setsAddName, URL: rawURL, SHA256: strings.ToLower(setsAddSHA256), PublicKey: setsAddKey}
+		if src.Name == "" {
+			src.Name = sets.SourceName(rawURL)
+		}
+		if strings.ContainsAny(src.Name, `/\`) {
+			return fmt.Errorf("invalid source name %q", src.Name)
+		}
+
+		src, digest, err := sets.Refresh(cmd.Context(), src)
+		if err != nil {
+			return err
+		}
+		if src.SHA256 == "" && src.PublicKey == "" {
+			src.SHA256 = digest
+			helpers.PrintInfo(fmt.Sprintf("Pinned sha256 %s; pass --pubkey to follow signed updates", digest))
+		}
+
+		replaced := false
+		for i, s := range sources {
+			if s.Name == src.Name {
+				sources[i] = src
+				replaced = true
+			}
+		}
+		if !replaced {
+			sources = append(sources, src)
+		}
+		if err := sets.SaveSources(sources); err != nil {
+			return err
+		}
+
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(src)
+			return nil
+		}
+		var names []string
+		for _, s := range sets.All() {
+			if s.Source == sets.SourceRemote+src.Name {
+				names = append(names, s.Name)
+			}
+		}
+		helpers.PrintSuccess(fmt.Sprintf("Added %s with sets: %s", src.Name, strings.Join(names, ", ")))
+		return nil
+	},
+}
+
+var setsRemoveCmd = &cobra.Command{
+	Use:               "remove <source>",
+	Short:             "Unsubscribe from remote set definitions",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: sourceNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sources, err := sets.LoadSources()
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(sources, func(s sets.Source) bool { return s.Name == args[0] })
+		if i < 0 {
+			return fmt.Errorf("unknown source %q (see `kettle sets list`)", args[0])
+		}
+		if err := sets.SaveSources(slices.Delete(sources, i, i+1)); err != nil {
+			return err
+		}
+		if err := sets.RemoveCache(args[0]); err != nil {
+			return fmt.Errorf("failed to remove cached sets: %w", err)
+		}
+		helpers.PrintSuccess(fmt.Sprintf("Removed %s", args[0]))
+		return nil
+	},
+}
+
+var setsSyncCmd = &cobra.Command{
+	Use:   "sync [source...]",
+	Short: "Refresh remote sets and install what they add",
+	Long: `Fetch and verify the latest definitions of every remote source, or of the
+named ones, then install the tools of their sets that are missing. A source
+that fails to fetch or verify keeps its cached definitions. When a source
+pinned by its checksum has changed, sync shows the new checksum and asks
+whether to accept it, defaulting to no.`,
+	ValidArgsFunction: sourceNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sources, err := sets.LoadSources()
+		if err != nil {
+			return err
+		}
+		if len(sources) == 0 {
+			return errors.New("no remote sets; add one with `kettle sets add <url>`")
+		}
+		for _, name := range args {
+			if !slices.ContainsFunc(sources, func(s sets.Source) bool { return s.Name == name }) {
+				return fmt.Errorf("unknown source %q (see `kettle sets list`)", name)
+			}
+		}
+
+		var errs []error
+		synced := map[string]bool{}
+		for i, src := range sources {
+			if len(args) > 0 && !slices.Contains(args, src.Name) {
+				continue
+			}
+			updated, digest, err := sets.Refresh(cmd.Context(), src)
+			if errors.Is(err, sets.ErrChanged) {
+				helpers.PrintInfo(fmt.Sprintf("%s changed since it was added: sha256 %s, pinned %s", src.Name, digest, src.SHA256))
+				if helpers.PromptYesNo(fmt.Sprintf("Accept the new definitions of %s?", src.Name), false) {
+					src.SHA256 = digest
+					updated, _, err = sets.Refresh(cmd.Context(), src)
+				}
+			}
+			if err != nil {
+				helpers.PrintFail(fmt.Sprintf("%s: %v", src.Name, err))
+				errs = append(errs, err)
+				continue
+			}
+			sources[i] = updated
+			synced[sets.SourceRemote+src.Name] = true
+			helpers.PrintSuccess(fmt.Sprintf("Refreshed %s", src.Name))
+		}
+		if len(synced) == 0 {
+			return errors.Join(errs...)
+		}
+		if err := sets.SaveSources(sources); err != nil {
+			return err
+		}
+		if setsSyncOnly {
+			return errors.Join(errs...)
+		}
+
+		var members []string
+		for _, s := range sets.All() {
+			if !synced[s.Source] {
+				continue
+			}
+			for _, tool := range s.Tools {
+				if !slices.Contains(members, tool) {
+					members = append(members, tool)
+				}
+			}
+		}
+		resolved, err := registry.Resolve(members)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		todo, present, err := pendingTools(cmd.Context(), resolved, nil)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		if len(todo) > 0 {
+			helpers.PrintInfo(fmt.Sprintf("Installing %s", strings.Join(toolNamesOf(todo), ", ")))
+		}
+		results, err := installGraph(cmd.Context(), todo, setsInstallJobs)
+		printInstallSummary(append(present, results...))
+		return errors.Join(append(errs, err)...)
+	},
+}
+
+// sourceNames completes the names of remote sources.
+func sourceNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	sources, _ := sets.LoadSources()
+	names := make([]string, 0, len(sources))
+	for _, s := range sources {
+		names = append(names, s.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func toolNamesOf(tools []registry.Tool) []string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+func init() {
+	sets.SetsCmd.AddCommand(setsListCmd)
+	sets.SetsCmd.AddCommand(setsShowCmd)
+	sets.SetsCmd.AddCommand(setsInstallCmd)
+	sets.SetsCmd.AddCommand(setsAddCmd)
+	sets.SetsCmd.AddCommand(setsRemoveCmd)
+	sets.SetsCmd.AddCommand(setsSyncCmd)
+
+	setsInstallCmd.Flags().IntVarP(&setsInstallJobs, "jobs", "j", 4, "Number of tools to install at once")
+	setsSyncCmd.Flags().IntVarP(&setsInstallJobs, "jobs", "j", 4, "Number of tools to install at once")
+	setsSyncCmd.Flags().BoolVar(&setsSyncOnly, "no-install", false, "Only refresh the definitions")
+	setsAddCmd.Flags().StringVar(&setsAddName, "name", "", "Name of the source (default: derived from the URL)")
+	setsAddCmd.Flags().StringVar(&setsAddSHA256, "sha256", "", "Pin the SHA-256 checksum of the definitions")
+	setsAddCmd.Flags().StringVar(&setsAddKey, "pubkey", "", "Base64 ed25519 public key that signs the definitions")
+}
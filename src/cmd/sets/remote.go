@@ -0,0 +1,346 @@
+package sets
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/internal/config"
+	"github.com/kettleofketchup/kettle/src/internal/paths"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	sourcesName = "remote-sets.json"
+	// gitPrefix marks a git repository URL; the fragment picks the file, or
+	// a directory holding sets.yaml.
+	gitPrefix = "git+"
+	// gitDefaultFile is read when the fragment names a directory.
+	gitDefaultFile = "sets.yaml"
+	// maxDefinitionSize bounds what is read from a remote source.
+	maxDefinitionSize = 1 << 20
+)
+
+// ErrChanged means the content of a source pinned by its checksum changed
+// since it was added.
+var ErrChanged = errors.New("it changed since it was added")
+
+// Source is a subscription to set definitions published elsewhere, such
+// as a team's canonical toolset.
+type Source struct {
+	Name string `json:"name" yaml:"name"`
+	URL  string `json:"url" yaml:"url"`
+	// SHA256 pins the exact content; such a source only changes when it
+	// is added again or a new checksum is accepted by sync.
+	SHA256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	// PublicKey is a base64 ed25519 key. The content must come with a
+	// valid signature in <file>.sig and may change as long as it does.
+	PublicKey string    `json:"public_key,omitempty" yaml:"public_key,omitempty"`
+	FetchedAt time.Time `json:"fetched_at,omitempty" yaml:"fetched_at,omitempty"`
+}
+
+// remoteFile is the format of a remote set definition: the sets: section
+// of the config file.
+type remoteFile struct {
+	Sets map[string]config.Set `yaml:"sets"`
+}
+
+// SourcesPath returns the file listing the remote sources.
+func SourcesPath() (string, error) {
+	dir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, sourcesName), nil
+}
+
+// LoadSources returns the remote sources that were added.
+func LoadSources() ([]Source, error) {
+	path, err := SourcesPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote sets: %w", err)
+	}
+	var sources []Source
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return sources, nil
+}
+
+// SaveSources writes the list of remote sources.
+func SaveSources(sources []Source) error {
+	path, err := SourcesPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(sources, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode remote sets: %w", err)
+	}
+	if err := helpers.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := helpers.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write remote sets: %w", err)
+	}
+	return nil
+}
+
+// SourceName derives a name for a source from its URL: the file or
+// directory the URL points at without extension, falling back to the
+// repository name and then the host.
+func SourceName(rawURL string) string {
+	u, err := url.Parse(strings.TrimPrefix(rawURL, gitPrefix))
+	if err != nil {
+		return "remote"
+	}
+	p := strings.TrimSuffix(u.Fragment, "/")
+	if path.Base(p) == gitDefaultFile {
+		p = path.Dir(p)
+	}
+	if p == "" || p == "." {
+		p = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git")
+	}
+	name := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	if name == "" || name == "." || name == "/" {
+		return u.Hostname()
+	}
+	return name
+}
+
+func cachePath(name string) (string, error) {
+	dir, err := paths.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sets", name+".yaml"), nil
+}
+
+// Refresh fetches src, verifies and parses it, and caches it. It returns
+// src with the fetch time updated and the SHA-256 of the content.
+func Refresh(ctx context.Context, src Source) (Source, string, error) {
+	data, sig, err := fetch(ctx, src.URL)
+	if err != nil {
+		return src, "", err
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	if err := verify(src, data, sig, digest); err != nil {
+		return src, digest, err
+	}
+	if _, err := parseRemote(data, src.URL); err != nil {
+		return src, digest, err
+	}
+
+	path, err := cachePath(src.Name)
+	if err != nil {
+		return src, digest, err
+	}
+	if err := helpers.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return src, digest, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := helpers.WriteFile(path, data, 0644); err != nil {
+		return src, digest, fmt.Errorf("failed to cache %s: %w", src.URL, err)
+	}
+	src.FetchedAt = time.Now().UTC()
+	return src, digest, nil
+}
+
+// RemoveCache deletes the cached definitions of the named source.
+func RemoveCache(name string) error {
+	path, err := cachePath(name)
+	if err != nil {
+		return err
+	}
+	if err := helpers.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func verify(src Source, data, sig []byte, digest string) error {
+	if src.SHA256 != "" && !strings.EqualFold(src.SHA256, digest) {
+		// A pin never follows updates on its own.
+		return fmt.Errorf("%w: %s has sha256 %s, pinned %s; %w, so review it and accept it in `kettle sets sync`, run `kettle sets add %s` again, or add it with --pubkey to follow signed updates",
+			helpers.ErrVerification, src.URL, digest, src.SHA256, ErrChanged, src.URL)
+	}
+	if src.PublicKey == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(src.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: public key must be a base64 ed25519 key", helpers.ErrVerification)
+	}
+	if sig == nil {
+		return fmt.Errorf("%w: %s has no signature (.sig)", helpers.ErrVerification, src.URL)
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig))); err == nil {
+		sig = decoded
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return fmt.Errorf("%w: bad signature for %s", helpers.ErrVerification, src.URL)
+	}
+	return nil
+}
+
+// fetch returns the definitions at rawURL and their signature, or a nil
+// signature when there is none.
+func fetch(ctx context.Context, rawURL string) (data, sig []byte, err error) {
+	if strings.HasPrefix(rawURL, gitPrefix) {
+		return fetchGit(ctx, strings.TrimPrefix(rawURL, gitPrefix))
+	}
+	data, err = fetchHTTP(ctx, rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err = fetchHTTP(ctx, rawURL+".sig")
+	if errors.Is(err, os.ErrNotExist) {
+		return data, nil, nil
+	}
+	return data, sig, err
+}
+
+func fetchHTTP(ctx context.Context, rawURL string) (data []byte, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer helpers.IOClose(resp.Body, &err)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", rawURL, os.ErrNotExist)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to fetch %s: %s", rawURL, resp.Status)
+	}
+	return readLimited(resp.Body, rawURL)
+}
+
+// fetchGit shallow-clones the repository into a temporary directory and
+// reads the file named by the fragment.
+func fetchGit(ctx context.Context, rawURL string) ([]byte, []byte, error) {
+	repo, file, _ := strings.Cut(rawURL, "#")
+	if strings.Contains(file, "..") {
+		return nil, nil, fmt.Errorf("invalid path %q in %s", file, rawURL)
+	}
+
+	tmp, err := os.MkdirTemp("", "kettle-sets-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	helpers.TrackTempFile(tmp)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+		helpers.UntrackTempFile(tmp)
+	}()
+
+	// Cloning only reads the remote, so it runs even during a dry run.
+	if _, err := helpers.Probe(ctx, "git", "clone", "--quiet", "--depth", "1", "--", repo, tmp); err != nil {
+		return nil, nil, fmt.Errorf("failed to clone %s: %w", repo, err)
+	}
+
+	target := filepath.Join(tmp, filepath.FromSlash(file))
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		target = filepath.Join(target, gitDefaultFile)
+	}
+	f, err := os.Open(target)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	defer func() { _ = f.Close() }()
+	data, err := readLimited(f, rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err := os.ReadFile(target + ".sig")
+	if err != nil {
+		sig = nil
+	}
+	return data, sig, nil
+}
+
+func readLimited(r io.Reader, name string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxDefinitionSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxDefinitionSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, maxDefinitionSize)
+	}
+	return data, nil
+}
+
+// parseRemote decodes remote set definitions. source names them in errors.
+func parseRemote(data []byte, source string) (map[string]config.Set, error) {
+	var file remoteFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+	if len(file.Sets) == 0 {
+		return nil, fmt.Errorf("%s defines no sets", source)
+	}
+	for name, set := range file.Sets {
+		if len(set.Tools) == 0 {
+			return nil, fmt.Errorf("%s: set %q has no tools", source, name)
+		}
+	}
+	return file.Sets, nil
+}
+
+// remoteSets returns the cached sets of every source. Sources that were
+// never fetched, or whose cache is unreadable, are skipped.
+func remoteSets() []Set {
+	sources, err := LoadSources()
+	if err != nil {
+		helpers.PrintError("Ignoring remote sets", err)
+		return nil
+	}
+	var out []Set
+	for _, src := range sources {
+		path, err := cachePath(src.Name)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		defs, err := parseRemote(data, path)
+		if err != nil {
+			helpers.PrintError(fmt.Sprintf("Ignoring remote sets from %s", src.Name), err)
+			continue
+		}
+		for name, s := range defs {
+			out = append(out, Set{Name: name, Description: s.Description, Tools: s.Tools, Source: SourceRemote + src.Name})
+		}
+	}
+	return out
+}
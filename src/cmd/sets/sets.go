@@ -2,29 +2,87 @@
 package sets
 
 import (
+	"sort"
+
+	"github.com/kettleofketchup/kettle/src/internal/config"
 	"github.com/spf13/cobra"
 )
 
-// SetsCmd represents the sets command
-var SetsCmd = &cobra.Command{
-	Use:   "sets",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+// Where a set is defined.
+const (
+	SourceBuiltin = "builtin"
+	SourceConfig  = "config"
+	// SourceRemote prefixes the name of the remote source a set came from.
+	SourceRemote = "remote:"
+)
+
+// Set is a named bundle of tools installed together.
+type Set struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Tools       []string `json:"tools" yaml:"tools"`
+	Source      string   `json:"source" yaml:"source"`
+}
+
+var builtin = []Set{
+	{Name: "terminal", Description: "Terminal, prompt and shell helpers", Tools: []string{"ghostty", "starship", "zoxide", "autoenv"}},
+	{Name: "go-dev", Description: "Go toolchain and linter", Tools: []string{"go", "golangci-lint"}},
+	{Name: "node-dev", Description: "Node.js and npm", Tools: []string{"node"}},
+}
+
+// All returns the built-in sets, the cached remote sets and the sets from
+// the config file, sorted by name. Remote sets replace built-in sets of
+// the same name, and config sets replace both.
+func All() []Set {
+	byName := map[string]Set{}
+	for _, s := range builtin {
+		s.Source = SourceBuiltin
+		byName[s.Name] = s
+	}
+	for _, s := range remoteSets() {
+		byName[s.Name] = s
+	}
+	for name, s := range config.Current().Sets {
+		byName[name] = Set{Name: name, Description: s.Description, Tools: s.Tools, Source: SourceConfig}
+	}
+	all := make([]Set, 0, len(byName))
+	for _, s := range byName {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Lookup returns the set called name.
+func Lookup(name string) (Set, bool) {
+	for _, s := range All() {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Set{}, false
+}
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+// Names completes set names.
+func Names(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	for _, s := range All() {
+		names = append(names, s.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
 }
 
-func init() {
-	// Here you will define your flags and configuration settings.
+// SetsCmd represents the sets command
+var SetsCmd = &cobra.Command{
+	Use:   "sets",
+	Short: "List, show and install named sets of tools",
+	Long: `Tool sets are named bundles of tools, such as terminal or go-dev, that are
+installed together. Define your own under sets: in the config file:
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// SetsCmd.PersistentFlags().String("foo", "", "A help for foo")
+  sets:
+    mine:
+      description: My usual tools
+      tools: [go, starship, zoxide]
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// SetsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+Teams can publish sets once and subscribe to them with kettle sets add.`,
 }
@@ -0,0 +1,130 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+)
+
+const managedName = "managed.json"
+
+// Drift statuses of a managed file.
+const (
+	DriftModified = "modified"
+	DriftMissing  = "missing"
+)
+
+// ManagedFile is what kettle apply last wrote to a file.
+type ManagedFile struct {
+	SHA256    string    `json:"sha256" yaml:"sha256"`
+	AppliedAt time.Time `json:"applied_at" yaml:"applied_at"`
+}
+
+// Drift is a managed file that changed since kettle apply wrote it.
+type Drift struct {
+	Path   string `json:"path" yaml:"path"`
+	Status string `json:"status" yaml:"status"`
+}
+
+// ManagedPath returns where the hashes of managed files are kept.
+func ManagedPath() (string, error) {
+	dir, err := helpers.GetKettleStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, managedName), nil
+}
+
+// LoadManaged returns the managed files keyed by path.
+func LoadManaged() (map[string]ManagedFile, error) {
+	files := map[string]ManagedFile{}
+	path, err := ManagedPath()
+	if err != nil {
+		return files, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return files, nil
+	}
+	if err != nil {
+		return files, fmt.Errorf("failed to read managed files: %w", err)
+	}
+	if err := json.Unmarshal(data, &files); err != nil {
+		return files, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// RecordManaged replaces the managed files with paths, hashed as they are
+// now. Like the tool manifest it is kettle's own bookkeeping, so it is
+// written directly and never during a dry run.
+func RecordManaged(paths []string) error {
+	if helpers.IsDryRun() {
+		return nil
+	}
+	files := map[string]ManagedFile{}
+	for _, p := range paths {
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		files[p] = ManagedFile{SHA256: sum, AppliedAt: time.Now().UTC()}
+	}
+
+	path, err := ManagedPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode managed files: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write managed files: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// CheckDrift returns the managed files that were edited or removed since
+// kettle apply last wrote them, sorted by path.
+func CheckDrift() ([]Drift, error) {
+	files, err := LoadManaged()
+	if err != nil {
+		return nil, err
+	}
+	var drift []Drift
+	for p, m := range files {
+		sum, err := hashFile(p)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			drift = append(drift, Drift{Path: p, Status: DriftMissing})
+		case err != nil:
+			return nil, err
+		case sum != m.SHA256:
+			drift = append(drift, Drift{Path: p, Status: DriftModified})
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Path < drift[j].Path })
+	return drift, nil
+}
+
+func hashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,227 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+)
+
+// file is a file whose whole content kettle owns.
+type file struct {
+	path    string
+	content []byte
+	mode    os.FileMode
+}
+
+// FileResource names the resource of the managed file at path in plans.
+func FileResource(path string) string {
+	return "file " + path
+}
+
+func (f file) Plan(ctx context.Context) (Change, error) {
+	c := Change{Resource: FileResource(f.path), Action: ActionNone}
+	current, err := os.ReadFile(f.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		c.Action = ActionCreate
+		c.Diff = helpers.DiffLines(nil, f.content)
+		return c, nil
+	case err != nil:
+		return c, fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+	if !bytes.Equal(current, f.content) {
+		c.Action = ActionUpdate
+		c.Diff = helpers.DiffLines(current, f.content)
+	}
+	if info, err := os.Stat(f.path); err == nil && info.Mode().Perm() != f.mode {
+		c.Action = ActionUpdate
+		c.Current = fmt.Sprintf("mode %o", info.Mode().Perm())
+		c.Desired = fmt.Sprintf("mode %o", f.mode)
+	}
+	return c, nil
+}
+
+func (f file) Apply(ctx context.Context) error {
+	if err := helpers.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(f.path), err)
+	}
+	if err := helpers.WriteFile(f.path, f.content, f.mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.path, err)
+	}
+	// WriteFile keeps the mode of an existing file.
+	return helpers.Chmod(f.path, f.mode)
+}
+
+// shellLine is a line in the kettle shell profile, or the system profile
+// with --system.
+type shellLine struct {
+	line string
+}
+
+func (s shellLine) Plan(ctx context.Context) (Change, error) {
+	c := Change{Resource: "shell " + s.line, Action: ActionNone}
+	profiles := []string{helpers.SystemProfile}
+	if !helpers.IsSystemInstall() {
+		info, err := helpers.GetShellInfo()
+		if err != nil {
+			return c, err
+		}
+		profiles = []string{info.ShellRCPath, info.KettlePath}
+	}
+	for _, p := range profiles {
+		found, err := helpers.ExistsInFile(p, s.line)
+		if err != nil {
+			return c, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		if found {
+			return c, nil
+		}
+	}
+	c.Action = ActionCreate
+	c.Diff = []string{"+ " + s.line}
+	return c, nil
+}
+
+func (s shellLine) Apply(ctx context.Context) error {
+	_, err := helpers.AddLineToKettleShellProfile(s.line)
+	return err
+}
+
+// gsetting is a GNOME setting changed with gsettings.
+type gsetting struct {
+	GSetting
+}
+
+func (g gsetting) Plan(ctx context.Context) (Change, error) {
+	c := Change{Resource: fmt.Sprintf("gsetting %s %s", g.Schema, g.Key), Action: ActionNone, Desired: g.Value}
+	current, err := gsettingsGet(ctx, g.Schema, g.Key)
+	if err != nil {
+		return c, err
+	}
+	c.Current = current
+	if unquote(current) != unquote(g.Value) {
+		c.Action = ActionUpdate
+	}
+	return c, nil
+}
+
+func (g gsetting) Apply(ctx context.Context) error {
+	return helpers.Run(ctx, "gsettings", "set", g.Schema, g.Key, g.Value)
+}
+
+const (
+	mediaKeysSchema     = "org.gnome.settings-daemon.plugins.media-keys"
+	customBindingSchema = mediaKeysSchema + ".custom-keybinding"
+	customBindingsPath  = "/org/gnome/settings-daemon/plugins/media-keys/custom-keybindings/"
+)
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// keybinding is a GNOME custom shortcut, stored under a kettle-<name>
+// path so kettle never touches shortcuts it did not create.
+type keybinding struct {
+	Keybinding
+}
+
+func (k keybinding) path() string {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(k.Name), "-"), "-")
+	return customBindingsPath + "kettle-" + slug + "/"
+}
+
+func (k keybinding) desired() string {
+	return fmt.Sprintf("%s runs %s", k.Binding, k.Command)
+}
+
+func (k keybinding) Plan(ctx context.Context) (Change, error) {
+	c := Change{Resource: "keybinding " + k.Name, Action: ActionNone, Desired: k.desired()}
+	list, err := customBindings(ctx)
+	if err != nil {
+		return c, err
+	}
+	if !slices.Contains(list, k.path()) {
+		c.Action = ActionCreate
+		return c, nil
+	}
+	schema := customBindingSchema + ":" + k.path()
+	var got []string
+	for _, key := range []string{"name", "binding", "command"} {
+		v, err := gsettingsGet(ctx, schema, key)
+		if err != nil {
+			return c, err
+		}
+		got = append(got, unquote(v))
+	}
+	c.Current = fmt.Sprintf("%s runs %s", got[1], got[2])
+	if got[0] != k.Name || got[1] != k.Binding || got[2] != k.Command {
+		c.Action = ActionUpdate
+	}
+	return c, nil
+}
+
+func (k keybinding) Apply(ctx context.Context) error {
+	schema := customBindingSchema + ":" + k.path()
+	for _, kv := range [][2]string{{"name", k.Name}, {"binding", k.Binding}, {"command", k.Command}} {
+		if err := helpers.Run(ctx, "gsettings", "set", schema, kv[0], quote(kv[1])); err != nil {
+			return err
+		}
+	}
+	list, err := customBindings(ctx)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(list, k.path()) {
+		return nil
+	}
+	list = append(list, k.path())
+	quoted := make([]string, len(list))
+	for i, p := range list {
+		quoted[i] = quote(p)
+	}
+	return helpers.Run(ctx, "gsettings", "set", mediaKeysSchema, "custom-keybindings", "["+strings.Join(quoted, ", ")+"]")
+}
+
+// customBindings returns the paths of the custom shortcuts.
+func customBindings(ctx context.Context) ([]string, error) {
+	v, err := gsettingsGet(ctx, mediaKeysSchema, "custom-keybindings")
+	if err != nil {
+		return nil, err
+	}
+	v = strings.TrimSpace(strings.TrimPrefix(v, "@as"))
+	v = strings.TrimSuffix(strings.TrimPrefix(v, "["), "]")
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = unquote(strings.TrimSpace(p)); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func gsettingsGet(ctx context.Context, schema, key string) (string, error) {
+	res, err := helpers.Probe(ctx, "gsettings", "get", schema, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s %s: %w", schema, key, err)
+	}
+	return strings.TrimSpace(res.Stdout), nil
+}
+
+// quote renders s as a GVariant string.
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// unquote strips GVariant string quotes, if any.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return strings.NewReplacer(`\\`, `\`, `\'`, `'`, `\"`, `"`).Replace(s[1 : len(s)-1])
+	}
+	return s
+}
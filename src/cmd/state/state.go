@@ -0,0 +1,212 @@
+// Package state describes a whole machine in one desired-state file, which
+// kettle plan compares with the machine and kettle apply converges it to.
+package state
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/internal/paths"
+	"gopkg.in/yaml.v3"
+)
+
+const fileName = "state.yaml"
+
+// What applying a change does.
+const (
+	ActionNone   = "none"
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	// ActionUnknown means the resource could not be checked.
+	ActionUnknown = "unknown"
+)
+
+// Document is a desired-state file.
+type Document struct {
+	// Tools maps tool names to a version: "" for any version, "latest",
+	// or a version prefix such as 1.23.
+	Tools map[string]string `yaml:"tools,omitempty" json:"tools,omitempty"`
+	// Sets are installed like their tools were listed with any version.
+	Sets []string `yaml:"sets,omitempty" json:"sets,omitempty"`
+	// Shell holds lines kept in the kettle shell profile.
+	Shell []string   `yaml:"shell,omitempty" json:"shell,omitempty"`
+	Files []FileSpec `yaml:"files,omitempty" json:"files,omitempty"`
+	Gnome Gnome      `yaml:"gnome,omitempty" json:"gnome,omitempty"`
+
+	// dir resolves relative file sources.
+	dir string
+}
+
+// FileSpec is a file kettle owns, such as a terminal config. Content is
+// given inline or read from Source, relative to the state file.
+type FileSpec struct {
+	Path    string `yaml:"path" json:"path"`
+	Content string `yaml:"content,omitempty" json:"content,omitempty"`
+	Source  string `yaml:"source,omitempty" json:"source,omitempty"`
+	// Mode is an octal permission string; it defaults to 0644.
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+}
+
+// Gnome holds GNOME settings and custom keyboard shortcuts.
+type Gnome struct {
+	Settings    []GSetting   `yaml:"settings,omitempty" json:"settings,omitempty"`
+	Keybindings []Keybinding `yaml:"keybindings,omitempty" json:"keybindings,omitempty"`
+}
+
+// GSetting is a gsettings key. Value uses gsettings syntax, so strings may
+// be quoted: 'prefer-dark'.
+type GSetting struct {
+	Schema string `yaml:"schema" json:"schema"`
+	Key    string `yaml:"key" json:"key"`
+	Value  string `yaml:"value" json:"value"`
+}
+
+// Keybinding is a GNOME custom keyboard shortcut.
+type Keybinding struct {
+	Name    string `yaml:"name" json:"name"`
+	Binding string `yaml:"binding" json:"binding"`
+	Command string `yaml:"command" json:"command"`
+}
+
+// Change is a difference between the machine and the desired state.
+type Change struct {
+	Resource string   `json:"resource" yaml:"resource"`
+	Action   string   `json:"action" yaml:"action"`
+	Current  string   `json:"current,omitempty" yaml:"current,omitempty"`
+	Desired  string   `json:"desired,omitempty" yaml:"desired,omitempty"`
+	Diff     []string `json:"diff,omitempty" yaml:"diff,omitempty"`
+	Error    string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Resource is a part of the machine kettle converges.
+type Resource interface {
+	// Plan compares the machine with the desired state.
+	Plan(ctx context.Context) (Change, error)
+	// Apply makes the machine match the desired state.
+	Apply(ctx context.Context) error
+}
+
+// DefaultPath returns the state file used without --file.
+func DefaultPath() (string, error) {
+	dir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load reads and validates the state file at path.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	doc := &Document{dir: filepath.Dir(path)}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := doc.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
+
+func (d *Document) validate() error {
+	for i, f := range d.Files {
+		switch {
+		case f.Path == "":
+			return fmt.Errorf("files[%d]: path is required", i)
+		case f.Content != "" && f.Source != "":
+			return fmt.Errorf("files[%d]: set content or source, not both", i)
+		}
+		if f.Mode != "" {
+			if _, err := strconv.ParseUint(f.Mode, 8, 32); err != nil {
+				return fmt.Errorf("files[%d]: mode %q is not an octal permission", i, f.Mode)
+			}
+		}
+	}
+	for i, s := range d.Gnome.Settings {
+		if s.Schema == "" || s.Key == "" {
+			return fmt.Errorf("gnome.settings[%d]: schema and key are required", i)
+		}
+	}
+	for i, k := range d.Gnome.Keybindings {
+		if k.Name == "" || k.Binding == "" || k.Command == "" {
+			return fmt.Errorf("gnome.keybindings[%d]: name, binding and command are required", i)
+		}
+	}
+	return nil
+}
+
+// Resources returns everything but the tools, in the order they are
+// applied: files, shell lines, GNOME settings and keybindings.
+func (d *Document) Resources() ([]Resource, error) {
+	var out []Resource
+	for _, f := range d.Files {
+		r, err := d.fileResource(f)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	for _, line := range d.Shell {
+		out = append(out, shellLine{line: line})
+	}
+	for _, s := range d.Gnome.Settings {
+		out = append(out, gsetting{s})
+	}
+	for _, k := range d.Gnome.Keybindings {
+		out = append(out, keybinding{k})
+	}
+	return out, nil
+}
+
+// ManagedFiles returns the paths of the files the state file manages,
+// with ~ expanded.
+func (d *Document) ManagedFiles() []string {
+	out := make([]string, len(d.Files))
+	for i, f := range d.Files {
+		out[i] = expandHome(f.Path)
+	}
+	return out
+}
+
+func (d *Document) fileResource(f FileSpec) (file, error) {
+	content := []byte(f.Content)
+	if f.Source != "" {
+		src := expandHome(f.Source)
+		if !filepath.IsAbs(src) {
+			src = filepath.Join(d.dir, src)
+		}
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return file{}, fmt.Errorf("failed to read source of %s: %w", f.Path, err)
+		}
+		content = data
+	}
+	mode := os.FileMode(0644)
+	if f.Mode != "" {
+		m, _ := strconv.ParseUint(f.Mode, 8, 32)
+		mode = os.FileMode(m)
+	}
+	return file{path: expandHome(f.Path), content: content, mode: mode}, nil
+}
+
+// expandHome replaces a leading ~ with the home directory. Without one the
+// path is left as it is, and fails when it is read or written.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := helpers.GetHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
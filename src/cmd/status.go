@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/spf13/cobra"
+)
+
+// Status describes kettle itself and the shell setup it manages.
+type Status struct {
+	Version        string `json:"version" yaml:"version"`
+	Commit         string `json:"commit" yaml:"commit"`
+	BuildDate      string `json:"build_date" yaml:"build_date"`
+	Executable     string `json:"executable,omitempty" yaml:"executable,omitempty"`
+	OS             string `json:"os" yaml:"os"`
+	Arch           string `json:"arch" yaml:"arch"`
+	Shell          string `json:"shell,omitempty" yaml:"shell,omitempty"`
+	ShellProfile   string `json:"shell_profile,omitempty" yaml:"shell_profile,omitempty"`
+	KettleProfile  string `json:"kettle_profile,omitempty" yaml:"kettle_profile,omitempty"`
+	ProfileSourced bool   `json:"profile_sourced" yaml:"profile_sourced"`
+	InstallDir     string `json:"install_dir,omitempty" yaml:"install_dir,omitempty"`
+	ToolsInstalled int    `json:"tools_installed" yaml:"tools_installed"`
+	ToolsKnown     int    `json:"tools_known" yaml:"tools_known"`
+}
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show kettle's version, shell setup and installed tool count",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st := Status{
+			Version:   Version,
+			Commit:    Commit,
+			BuildDate: Date,
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+		}
+		if exe, err := os.Executable(); err == nil {
+			st.Executable = exe
+		}
+		if dir, err := helpers.GetInstallDir(); err == nil {
+			st.InstallDir = dir
+		}
+		if shell, err := helpers.GetShellInfo(); err == nil {
+			st.Shell = shell.Type
+			st.ShellProfile = shell.ShellRCPath
+			st.KettleProfile = shell.KettlePath
+			st.ProfileSourced, _ = helpers.ExistsInFile(shell.ShellRCPath, "source "+shell.KettlePath)
+		}
+		for _, tool := range registry.All() {
+			st.ToolsKnown++
+			if registry.Detect(cmd.Context(), tool).Installed {
+				st.ToolsInstalled++
+			}
+		}
+
+		if helpers.IsStructuredOutput() {
+			helpers.SetReportData(st)
+			return nil
+		}
+
+		rows := [][]string{
+			{"Version", GetVersion()},
+			{"Executable", st.Executable},
+			{"Platform", st.OS + "/" + st.Arch},
+			{"Shell", st.Shell},
+			{"Shell profile", st.ShellProfile},
+			{"Kettle profile", st.KettleProfile},
+			{"Profile sourced", yesNo(st.ProfileSourced)},
+			{"Install dir", st.InstallDir},
+			{"Tools installed", fmt.Sprintf("%d of %d", st.ToolsInstalled, st.ToolsKnown)},
+		}
+		fmt.Println(renderTable([]string{"Setting", "Value"}, rows))
+		return nil
+	},
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
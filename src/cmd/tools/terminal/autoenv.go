@@ -2,9 +2,13 @@ package terminal
 
 // autoenv.go
 import (
-	"strings"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 
 	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
 	"github.com/spf13/cobra"
 )
 
@@ -20,7 +24,7 @@ This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 }
 
-func AddAutoenvToProfile() {
+func AddAutoenvToProfile() error {
 	script := `if command -v npm >/dev/null; then
   NPM_ROOT=$(npm root -g 2>/dev/null)
   if [ -n "$NPM_ROOT" ] && [ -f "$NPM_ROOT/@hyperupcall/autoenv/activate.sh" ]; then
@@ -28,39 +32,73 @@ func AddAutoenvToProfile() {
   fi
 fi`
 
-	helpers.AddLineToKettleShellProfile(script)
+	_, err := helpers.AddLineToKettleShellProfile(script)
+	return err
 }
 
 var autoenvInstallCmd = &cobra.Command{
 	Use:   "install",
 	Short: "Installs autoenv",
 	Long:  `Installs autoenv.`,
-	Run: func(cmd *cobra.Command, args []string) {
-
-		if !helpers.CommandExists("git") {
-			helpers.PrintFail("git is not installed. Cannot install autoenv.")
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return installAutoenv(cmd.Context())
+	},
+}
 
-		err := helpers.RunCmd("git clone 'https://github.com/hyperupcall/autoenv' ~/.autoenv", false)
+func installAutoenv(ctx context.Context) error {
+	if !helpers.CommandExists("git") {
+		helpers.PrintFail("git is not installed. Cannot install autoenv.")
+		return errors.New("git is required to install autoenv")
+	}
 
-		if err != nil && strings.Contains(err.Error(), "128") {
-			helpers.PrintInfo("autoenv is already cloned.")
-			err := helpers.RunCmd("cd ~/.autoenv; git pull", false)
-			if err != nil {
-				helpers.PrintError("Failed to update autoenv", err)
-			} else {
-				helpers.PrintSuccess("Updated autoenv.")
-			}
+	home, err := helpers.GetHomeDir()
+	if err != nil {
+		return err
+	}
+	autoenvDir := filepath.Join(home, ".autoenv")
+	_, err = helpers.Exec(ctx, helpers.Command{
+		Args: []string{"git", "clone", "https://github.com/hyperupcall/autoenv", autoenvDir},
+	})
 
+	var exitErr *helpers.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode == 128 {
+		helpers.PrintInfo("autoenv is already cloned.")
+		_, err := helpers.Exec(ctx, helpers.Command{Args: []string{"git", "-C", autoenvDir, "pull"}})
+		if err != nil {
+			helpers.PrintError("Failed to update autoenv", err)
 		} else {
-			helpers.PrintError("Failed to clone autoenv repository", err)
-			panic(err)
+			helpers.PrintSuccess("Updated autoenv.")
 		}
 
-		helpers.AddLineToKettleShellProfile("source ~/.autoenv/activate.sh")
-	},
+	} else if err != nil {
+		helpers.PrintError("Failed to clone autoenv repository", err)
+		return err
+	}
+
+	_, err = helpers.AddLineToKettleShellProfile("source ~/.autoenv/activate.sh")
+	return err
 }
 
 func init() {
 	AutoenvCmd.AddCommand(autoenvInstallCmd)
+
+	registry.Register(registry.Tool{
+		Name:        "autoenv",
+		Group:       "terminal",
+		Description: "Runs .env scripts when entering a directory",
+		Requires:    []string{"git"},
+		Check: func() (string, bool) {
+			home, err := helpers.GetHomeDir()
+			if err != nil {
+				return "", false
+			}
+			script := filepath.Join(home, ".autoenv", "activate.sh")
+			if _, err := os.Stat(script); err != nil {
+				return "", false
+			}
+			return script, true
+		},
+		Installer: installAutoenv,
+		Install:   autoenvInstallCmd,
+	})
 }
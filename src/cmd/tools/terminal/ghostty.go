@@ -2,11 +2,15 @@ package terminal
 
 // ghostty.go
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/kettleofketchup/kettle/src/internal/paths"
 	"github.com/spf13/cobra"
 )
 
@@ -26,38 +30,55 @@ var ghosttyInstallCmd = &cobra.Command{
 	Use:   "install",
 	Short: "Installs ghostty",
 	Long:  `Installs ghostty for the current operating system.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if !helpers.IsUbuntu() && !helpers.IsDarwin() {
-			helpers.PrintFail("Unsupported OS. Only Ubuntu and macOS are supported.")
-			return
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return installGhostty(cmd.Context())
+	},
+}
 
-		if helpers.CommandExists("ghostty") {
-			fmt.Println("ghostty is already installed.")
-			return
-		}
+func installGhostty(ctx context.Context) error {
+	if !helpers.IsUbuntu() && !helpers.IsDarwin() {
+		helpers.PrintFail("Unsupported OS. Only Ubuntu and macOS are supported.")
+		return errors.New("unsupported OS")
+	}
 
-		if helpers.IsDarwin() {
-			if !helpers.CommandExists("brew") {
-				helpers.PrintFail("Homebrew is not installed. Cannot install ghostty.")
-				return
-			}
-			err := helpers.RunCmd("brew install ghostty")
-			if err != nil {
-				helpers.PrintFail("Failed to install ghostty with brew")
-			}
-		} else if helpers.IsUbuntu() {
-			err := helpers.RunCmd("sudo snap install ghostty --classic")
-			if err != nil {
-				helpers.PrintFail("Failed to install ghostty with snap")
-			}
+	installed := helpers.CommandExists("ghostty")
+	if installed && !helpers.IsReinstall() {
+		helpers.PrintInfo("ghostty is already installed.")
+		return fmt.Errorf("ghostty: %w", helpers.ErrAlreadyInstalled)
+	}
+
+	if helpers.IsDarwin() {
+		if !helpers.CommandExists("brew") {
+			helpers.PrintFail("Homebrew is not installed. Cannot install ghostty.")
+			return errors.New("homebrew is not installed")
 		}
-	},
+		action := "install"
+		if installed {
+			action = "upgrade"
+		}
+		err := helpers.Run(ctx, "brew", action, "ghostty")
+		if err != nil {
+			helpers.PrintFail("Failed to install ghostty with brew")
+			return err
+		}
+	} else if helpers.IsUbuntu() {
+		action := "install"
+		if installed {
+			action = "refresh"
+		}
+		err := helpers.RunAsRoot(ctx, "snap", action, "ghostty", "--classic")
+		if err != nil {
+			helpers.PrintFail("Failed to install ghostty with snap")
+			return err
+		}
+	}
+	return nil
 }
+
 var ghosttyCreateToggleScriptCmd = &cobra.Command{
 	Use:   "create-toggle-script",
 	Short: "Creates the ghostty toggle script",
-	Long:  `Creates the ghostty toggle script at ~/bin/ghostty-toggle.sh.`,
+	Long:  `Creates the ghostty toggle script at ghostty-toggle.sh in $XDG_BIN_HOME (~/.local/bin by default).`,
 	Run: func(cmd *cobra.Command, args []string) {
 		scriptPath := getScriptPath()
 		const scriptContent = `#!/usr/bin/env bash
@@ -75,12 +96,12 @@ tdrop -ma -w 5000 -h 5000 -y 0 -a $CMD`
 
 		// Create bin directory if it doesn't exist
 		binDir := filepath.Dir(scriptPath)
-		if err := os.MkdirAll(binDir, 0755); err != nil {
+		if err := helpers.MkdirAll(binDir, 0755); err != nil {
 			helpers.PrintFail(fmt.Sprintf("Failed to create bin directory: %v", err))
 			return
 		}
 
-		err := os.WriteFile(scriptPath, []byte(scriptContent), 0755)
+		err := helpers.WriteFile(scriptPath, []byte(scriptContent), 0755)
 		if err != nil {
 			helpers.PrintFail(fmt.Sprintf("Failed to create toggle script: %v", err))
 			return
@@ -92,13 +113,13 @@ tdrop -ma -w 5000 -h 5000 -y 0 -a $CMD`
 
 func getScriptPath() string {
 
-	homeDir, err := os.UserHomeDir()
+	binDir, err := paths.BinDir()
 	if err != nil {
-		helpers.PrintFail("Failed to get user home directory")
+		helpers.PrintFail("Failed to get user bin directory")
 		return ""
 	}
 
-	scriptPath := filepath.Join(homeDir, "bin", "ghostty-toggle.sh")
+	scriptPath := filepath.Join(binDir, "ghostty-toggle.sh")
 	return scriptPath
 }
 
@@ -128,28 +149,28 @@ var ghosttyBindF1Cmd = &cobra.Command{
 		}
 
 		// Register the custom binding path - this sets the array of custom keybindings
-		err := helpers.RunCmd(fmt.Sprintf("gsettings set %s custom-keybindings \"['%s']\"", schema, path))
+		err := helpers.Run(cmd.Context(), "gsettings", "set", schema, "custom-keybindings", fmt.Sprintf("['%s']", path))
 		if err != nil {
 			helpers.PrintFail("Failed to set custom-keybindings")
 			return
 		}
 
 		// Configure the binding name
-		err = helpers.RunCmd(fmt.Sprintf("gsettings set %s.custom-keybinding:%s name \"%s\"", schema, path, name))
+		err = helpers.Run(cmd.Context(), "gsettings", "set", schema+".custom-keybinding:"+path, "name", name)
 		if err != nil {
 			helpers.PrintFail("Failed to set binding name")
 			return
 		}
 
 		// Configure the binding command
-		err = helpers.RunCmd(fmt.Sprintf("gsettings set %s.custom-keybinding:%s command \"%s\"", schema, path, scriptPath))
+		err = helpers.Run(cmd.Context(), "gsettings", "set", schema+".custom-keybinding:"+path, "command", scriptPath)
 		if err != nil {
 			helpers.PrintFail("Failed to set binding command")
 			return
 		}
 
 		// Configure the key binding
-		err = helpers.RunCmd(fmt.Sprintf("gsettings set %s.custom-keybinding:%s binding \"%s\"", schema, path, key))
+		err = helpers.Run(cmd.Context(), "gsettings", "set", schema+".custom-keybinding:"+path, "binding", key)
 		if err != nil {
 			helpers.PrintFail("Failed to set key binding")
 			return
@@ -169,13 +190,13 @@ var ghosttyUnbindF1Cmd = &cobra.Command{
 		const key = "F1"
 		scriptPath := getScriptPath()
 
-		err := helpers.RunCmd(fmt.Sprintf("gsettings reset %s custom-keybindings", schema))
+		err := helpers.Run(cmd.Context(), "gsettings", "reset", schema, "custom-keybindings")
 		if err != nil {
 			helpers.PrintFail("Failed to reset custom-keybindings")
 			return
 		}
 
-		err = helpers.RunCmd(fmt.Sprintf("gsettings reset-recursively %s.custom-keybinding:%s", schema, path))
+		err = helpers.Run(cmd.Context(), "gsettings", "reset-recursively", schema+".custom-keybinding:"+path)
 		if err != nil {
 			helpers.PrintFail("Failed to reset-recursively")
 			return
@@ -191,4 +212,13 @@ func init() {
 	GhosttyCmd.AddCommand(ghosttyUnbindF1Cmd)
 	GhosttyCmd.AddCommand(ghosttyCreateToggleScriptCmd)
 
+	registry.Register(registry.Tool{
+		Name:        "ghostty",
+		Group:       "terminal",
+		Description: "Fast, native terminal emulator",
+		Latest:      registry.GitHubLatest("ghostty-org", "ghostty"),
+		Installer:   installGhostty,
+		Install:     ghosttyInstallCmd,
+	})
+
 }
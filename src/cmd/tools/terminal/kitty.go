@@ -2,9 +2,13 @@ package terminal
 
 // install kitty packages
 import (
+	"context"
 	"fmt"
+	"path/filepath"
 
 	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/kettleofketchup/kettle/src/internal/paths"
 	"github.com/spf13/cobra"
 )
 
@@ -24,25 +28,67 @@ var kittyInstallCmd = &cobra.Command{
 	Use:   "install",
 	Short: "Installs Kitty",
 	Long:  `Installs Kitty.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		commands := []string{
-			"curl -L https://sw.kovidgoyal.net/kitty/installer.sh | sh /dev/stdin",
-			"ln -sf ~/.local/kitty.app/bin/kitty ~/.local/kitty.app/bin/kitten ~/.local/bin/",
-			"cp ~/.local/kitty.app/share/applications/kitty.desktop ~/.local/share/applications/",
-			"cp ~/.local/kitty.app/share/applications/kitty-open.desktop ~/.local/share/applications/",
-			`sed -i "s|Icon=kitty|Icon=/home/$USER/.local/kitty.app/share/icons/hicolor/256x256/apps/kitty.png|g" ~/.local/share/applications/kitty*.desktop`,
-			`sed -i "s|Exec=kitty|Exec=/home/$USER/.local/kitty.app/bin/kitty|g" ~/.local/share/applications/kitty*.desktop`,
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return installKitty(cmd.Context())
+	},
+}
+
+func installKitty(ctx context.Context) error {
+	binDir, err := paths.BinDir()
+	if err != nil {
+		return err
+	}
+	// The installer puts kitty.app in dest, by default ~/.local.
+	dataDir, err := paths.DataDir()
+	if err != nil {
+		return err
+	}
+	kittyApp := filepath.Join(dataDir, "kitty.app")
+	dataHome, err := paths.DataHome()
+	if err != nil {
+		return err
+	}
+	applications := filepath.Join(dataHome, "applications")
+	desktopFiles := []string{
+		filepath.Join(applications, "kitty.desktop"),
+		filepath.Join(applications, "kitty-open.desktop"),
+	}
 
-		for _, command := range commands {
-			err := helpers.RunCmd(command)
-			if err != nil {
-				helpers.PrintFail(fmt.Sprintf("Failed to execute command: %s", command))
-			}
+	if err := helpers.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := helpers.DownloadAndRunInstallScript(ctx, "https://sw.kovidgoyal.net/kitty/installer.sh", "kitty_installer.sh", "dest="+dataDir); err != nil {
+		helpers.PrintFail("Failed to run the kitty installer")
+		return err
+	}
+
+	commands := [][]string{
+		{"ln", "-sf", filepath.Join(kittyApp, "bin", "kitty"), filepath.Join(kittyApp, "bin", "kitten"), binDir + "/"},
+		{"cp", filepath.Join(kittyApp, "share", "applications", "kitty.desktop"), applications + "/"},
+		{"cp", filepath.Join(kittyApp, "share", "applications", "kitty-open.desktop"), applications + "/"},
+		append([]string{"sed", "-i", "s|Icon=kitty|Icon=" + filepath.Join(kittyApp, "share", "icons", "hicolor", "256x256", "apps", "kitty.png") + "|g"}, desktopFiles...),
+		append([]string{"sed", "-i", "s|Exec=kitty|Exec=" + filepath.Join(kittyApp, "bin", "kitty") + "|g"}, desktopFiles...),
+	}
+
+	for _, command := range commands {
+		err := helpers.Run(ctx, command...)
+		if err != nil {
+			helpers.PrintFail(fmt.Sprintf("Failed to execute command: %s", helpers.ShellQuote(command...)))
+			return err
 		}
-	},
+	}
+	return nil
 }
 
 func init() {
 	KittyCmd.AddCommand(kittyInstallCmd)
+
+	registry.Register(registry.Tool{
+		Name:        "kitty",
+		Group:       "terminal",
+		Description: "GPU based terminal emulator",
+		Latest:      registry.GitHubLatest("kovidgoyal", "kitty"),
+		Installer:   installKitty,
+		Install:     kittyInstallCmd,
+	})
 }
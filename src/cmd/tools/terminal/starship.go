@@ -1,9 +1,13 @@
 package terminal
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
 
 	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
 	"github.com/spf13/cobra"
 )
 
@@ -17,49 +21,46 @@ var StarshipCmd = &cobra.Command{
 var starshipInstallCmd = &cobra.Command{
 	Use:   "install",
 	Short: "Install Starship cross-shell prompt",
-	Long:  `Downloads and installs Starship using the official installation script from starship.rs.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		err := installStarship()
+	Long:  `Downloads and installs the latest Starship release from GitHub.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := installStarship(cmd.Context())
+		if errors.Is(err, helpers.ErrAlreadyInstalled) {
+			return err
+		}
 		if err != nil {
 			helpers.PrintError("Failed to install Starship", err)
-		} else {
-			helpers.PrintSuccess("Starship installed successfully!")
-			helpers.PrintInfo("Starship initialization has been added to your kettle shell profile")
+			return err
 		}
+		helpers.PrintSuccess("Starship installed successfully!")
+		helpers.PrintInfo("Starship initialization has been added to your kettle shell profile")
+		return nil
 	},
 }
 
-func installStarship() error {
+func installStarship(ctx context.Context) error {
 	// Check if starship is already installed
-	if helpers.CommandExists("starship") {
-		if !helpers.PromptYesNo("Starship is already installed. Do you want to reinstall it?") {
-			return nil
+	if helpers.CommandExists("starship") && !helpers.IsReinstall() {
+		if !helpers.PromptYesNo("Starship is already installed. Do you want to reinstall it?", false) {
+			return fmt.Errorf("starship: %w", helpers.ErrAlreadyInstalled)
 		}
 	}
 
-	helpers.PrintInfo("Downloading Starship installation script...")
-
-	// Use the helpers package to download and run the install script
-	url := "https://starship.rs/install.sh"
-	filename := "starship_install.sh"
-	if err := helpers.DownloadFile(filename, url); err != nil {
-
-		return fmt.Errorf("failed to download Starship install script: %w", err)
-	}
-
-	cmd := `sh -c "./starship_install.sh -b ~/.local/bin -f" `
+	return installStarshipVersion(ctx, "")
+}
 
-	if err := helpers.RunCmd(cmd); err != nil {
-		return fmt.Errorf("failed to run Starship install script: %w", err)
+// installStarshipVersion installs version of Starship from its GitHub
+// release, or the latest release when version is "", keeping the previous
+// versions for `kettle rollback starship`.
+func installStarshipVersion(ctx context.Context, version string) error {
+	if _, err := helpers.InstallGithubBinary(ctx, "starship", "starship", "starship", version, "starship"); err != nil {
+		return fmt.Errorf("failed to install Starship: %w", err)
 	}
 
 	// Add starship init to shell profile
-	addStarshipToShellProfile()
-
-	return nil
+	return addStarshipToShellProfile()
 }
 
-func addStarshipToShellProfile() {
+func addStarshipToShellProfile() error {
 	shell := helpers.GetCurrentShell()
 	var initLine string
 
@@ -73,19 +74,34 @@ func addStarshipToShellProfile() {
 	default:
 		helpers.PrintInfo(fmt.Sprintf("Manual setup required for shell: %s", shell))
 		helpers.PrintInfo("Add the appropriate starship init command to your shell profile")
-		return
+		return nil
 	}
 
 	// Add to kettle shell profile instead of main profile for better organization
-	added := helpers.AddLineToShellProfile(initLine)
+	added, err := helpers.AddLineToShellProfile(initLine)
+	if err != nil {
+		return err
+	}
 	if added {
 		helpers.PrintSuccess(fmt.Sprintf("Added Starship initialization to %s profile", shell))
 		helpers.PrintInfo("Restart your shell or source your profile to activate Starship")
 	} else {
 		helpers.PrintInfo(fmt.Sprintf("Starship initialization already present in %s profile", shell))
 	}
+	return nil
 }
 
 func init() {
 	StarshipCmd.AddCommand(starshipInstallCmd)
+
+	registry.Register(registry.Tool{
+		Name:           "starship",
+		Group:          "terminal",
+		Description:    "Cross-shell prompt",
+		VersionPattern: regexp.MustCompile(`starship (\d+\.\d+\.\d+)`),
+		Latest:         registry.GitHubLatest("starship", "starship"),
+		InstallVersion: installStarshipVersion,
+		Installer:      installStarship,
+		Install:        starshipInstallCmd,
+	})
 }
@@ -1,9 +1,11 @@
 package terminal
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
 	"github.com/spf13/cobra"
 )
 
@@ -23,27 +25,53 @@ var zoxideInstallCmd = &cobra.Command{
 	Use:   "install",
 	Short: "Installs zoxide",
 	Long:  `Installs zoxide.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if helpers.CommandExists("zoxide") {
-			fmt.Println("Zoxide is already installed.")
-			return
-		}
-
-		err := helpers.RunCmd("curl -sSfL https://raw.githubusercontent.com/ajeetdsouza/zoxide/main/install.sh | sh")
-		if err != nil {
-			helpers.PrintFail("Failed to install zoxide")
-		} else {
-			helpers.PrintSuccess("Zoxide installed.")
-		}
-		if !helpers.AddLineToKettleShellProfile(`eval "$(zoxide init --cmd z zsh)"`) {
-			helpers.PrintFail("Failed to add zoxide initialization to shell profile")
-			return
-		}
-		helpers.PrintSuccess("Added zoxide initialization to shell profile")
-
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return installZoxide(cmd.Context())
 	},
 }
 
+func installZoxide(ctx context.Context) error {
+	if helpers.CommandExists("zoxide") && !helpers.IsReinstall() {
+		helpers.PrintInfo("Zoxide is already installed.")
+		return fmt.Errorf("zoxide: %w", helpers.ErrAlreadyInstalled)
+	}
+	return installZoxideVersion(ctx, "")
+}
+
+// installZoxideVersion installs version of zoxide from its GitHub
+// release, or the latest release when version is "", keeping the previous
+// versions for `kettle rollback zoxide`.
+func installZoxideVersion(ctx context.Context, version string) error {
+	version, err := helpers.InstallGithubBinary(ctx, "zoxide", "ajeetdsouza", "zoxide", version, "zoxide")
+	if err != nil {
+		helpers.PrintFail("Failed to install zoxide")
+		return err
+	}
+	helpers.PrintSuccess(fmt.Sprintf("Zoxide %s installed.", version))
+	shell, err := helpers.GetShellInfo()
+	if err != nil {
+		return err
+	}
+	added, err := helpers.AddLineToKettleShellProfile(fmt.Sprintf(`eval "$(zoxide init --cmd z %s)"`, shell.Type))
+	if err != nil {
+		return fmt.Errorf("failed to add zoxide initialization to shell profile: %w", err)
+	}
+	if added {
+		helpers.PrintSuccess("Added zoxide initialization to shell profile")
+	}
+	return nil
+}
+
 func init() {
 	ZoxideCmd.AddCommand(zoxideInstallCmd)
+
+	registry.Register(registry.Tool{
+		Name:           "zoxide",
+		Group:          "terminal",
+		Description:    "Smarter cd command",
+		Latest:         registry.GitHubLatest("ajeetdsouza", "zoxide"),
+		InstallVersion: installZoxideVersion,
+		Installer:      installZoxide,
+		Install:        zoxideInstallCmd,
+	})
 }
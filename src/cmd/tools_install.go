@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/kettleofketchup/kettle/src/cmd/tools"
+	"github.com/spf13/cobra"
+)
+
+var toolsInstallJobs int
+
+// toolsInstallCmd installs several tools at once.
+var toolsInstallCmd = &cobra.Command{
+	Use:   "install <tool>...",
+	Short: "Install several tools in parallel, dependencies first",
+	Long: `Install the named tools and the tools they depend on (golangci-lint
+needs go). Independent tools install in parallel, up to --jobs at a time;
+a tool waits for its dependencies and is skipped if one of them fails.`,
+	Example:           `  kettle tools install go golangci-lint node starship zoxide`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: toolNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resolved, err := registry.Resolve(args)
+		if err != nil {
+			return err
+		}
+		todo, present, err := pendingTools(cmd.Context(), resolved, args)
+		if err != nil {
+			return err
+		}
+		results, err := installGraph(cmd.Context(), todo, toolsInstallJobs)
+		printInstallSummary(append(present, results...))
+		return err
+	},
+}
+
+func init() {
+	tools.ToolsCmd.AddCommand(toolsInstallCmd)
+
+	toolsInstallCmd.Flags().IntVarP(&toolsInstallJobs, "jobs", "j", 4, "Number of tools to install at once")
+}
@@ -1,29 +1,28 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
+	"github.com/google/go-github/github"
 	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/internal/config"
 	"github.com/spf13/cobra"
 )
 
 const (
-	githubReleasesURL    = "https://github.com/kettleofketchup/kettle/releases/latest/download/kettle"
-	githubAPIReleasesURL = "https://api.github.com/repos/kettleofketchup/kettle/releases/latest"
+	kettleOwner = "kettleofketchup"
+	kettleRepo  = "kettle"
 )
 
-// GitHubRelease represents the structure of a GitHub release API response
-type GitHubRelease struct {
-	TagName string `json:"tag_name"`
-	Name    string `json:"name"`
-}
+var (
+	updateChannel  string
+	updateVersion  string
+	updateRollback bool
+)
 
 // updateCmd represents the update command
 var updateCmd = &cobra.Command{
@@ -31,163 +30,185 @@ var updateCmd = &cobra.Command{
 	Short: "Update kettle to the latest version",
 	Long: `Download and install the latest version of kettle from GitHub releases.
 This command handles the case where the current binary might be in use
-by using a temporary file and atomic replacement.`,
+by using atomic replacement.
+
+The update channel picks which releases count as the latest:
+
+  stable    releases only (default)
+  beta      releases and prereleases
+  nightly   the most recently published build, nightlies included
+
+On nightly, kettle updates whenever the newest build is not the one that
+is running. Without --version kettle never downgrades.
+
+--channel switches channel and saves it as update_channel in the config
+file. --version installs that exact release instead, which can also be a
+downgrade; kettle asks first, and the answer defaults to no.
+
+The release asset for this OS and architecture is downloaded next to the
+current binary, checked against the checksum published with the release,
+unpacked, checked to be built for this platform, and run once with "version"
+before it replaces anything. The replaced binary is kept as kettle.old;
+--rollback puts it back.`,
+	Example: `  kettle update
+  kettle update --channel beta
+  kettle update --version v0.9.3
+  kettle update --rollback`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return updateKettle()
+		if updateRollback {
+			return rollbackKettle(cmd.Context())
+		}
+		channel := config.Current().UpdateChannel
+		if updateChannel != "" {
+			if !slices.Contains(updateChannels, updateChannel) {
+				return fmt.Errorf("unknown channel %q (want %s)", updateChannel, strings.Join(updateChannels, ", "))
+			}
+			if updateChannel != channel {
+				path, err := writeConfigKey("update_channel", updateChannel)
+				if err != nil {
+					return err
+				}
+				helpers.PrintInfo(fmt.Sprintf("Update channel set to %s in %s", updateChannel, path))
+			}
+			channel = updateChannel
+		}
+		return updateKettle(cmd.Context(), channel, updateVersion)
 	},
 }
 
-func updateKettle() error {
-	helpers.PrintInfo("Checking for latest kettle version...")
-
-	// Get latest release information from GitHub API
-	latestRelease, err := getLatestRelease()
-	if err != nil {
-		helpers.PrintError("Failed to fetch latest release information", err)
-		return err
-	}
-
-	latestVersion := strings.TrimPrefix(latestRelease.TagName, "v")
+var updateChannels = []string{config.ChannelStable, config.ChannelBeta, config.ChannelNightly}
 
-	// Get clean version without 'v' prefix
-	currentVersion := strings.TrimPrefix(Version, "v")
+// isNightly reports whether release is a nightly build.
+func isNightly(release *github.RepositoryRelease) bool {
+	return strings.Contains(strings.ToLower(release.GetTagName()), "nightly")
+}
 
-	helpers.PrintInfo(fmt.Sprintf("Current version: v%s", currentVersion))
-	helpers.PrintInfo(fmt.Sprintf("Latest version: v%s", latestVersion)) // Compare versions
-	if compareVersions(currentVersion, latestVersion) >= 0 {
-		helpers.PrintSuccess("You are already running the latest version!")
-		return nil
+// channelRelease picks the latest release on channel: the highest stable
+// version, the highest version including prereleases for beta, or the
+// most recently published release of any kind for nightly. Drafts never
+// count.
+func channelRelease(releases []*github.RepositoryRelease, channel string) *github.RepositoryRelease {
+	var best *github.RepositoryRelease
+	for _, r := range releases {
+		if r.GetDraft() {
+			continue
+		}
+		switch channel {
+		case config.ChannelNightly:
+			if best == nil || r.GetPublishedAt().After(best.GetPublishedAt().Time) {
+				best = r
+			}
+			continue
+		case config.ChannelBeta:
+			if isNightly(r) {
+				continue
+			}
+		default:
+			if isNightly(r) || r.GetPrerelease() {
+				continue
+			}
+		}
+		if best == nil || helpers.CompareVersions(r.GetTagName(), best.GetTagName()) > 0 {
+			best = r
+		}
 	}
+	return best
+}
 
-	helpers.PrintInfo("A newer version is available. Starting update...")
-
-	// Get the current executable path
-	currentExe, err := os.Executable()
-	if err != nil {
-		helpers.PrintError("Failed to get current executable path", err)
-		return err
+// isRunningBuild reports whether release is the build of kettle that is
+// running: the same tag, or the same commit when the release records one.
+func isRunningBuild(release *github.RepositoryRelease) bool {
+	if strings.TrimPrefix(release.GetTagName(), "v") == strings.TrimPrefix(Version, "v") {
+		return true
 	}
-
-	// Create a temporary file for the download
-	tmpDir := os.TempDir()
-	tmpFile := filepath.Join(tmpDir, "kettle-update")
-
-	helpers.PrintInfo("Downloading latest kettle binary...")
-
-	if err := helpers.DownloadFile(tmpFile, githubReleasesURL); err != nil {
-		helpers.PrintError("Failed to download latest version", err)
-		return err
+	target := release.GetTargetCommitish()
+	if len(Commit) < 7 || len(target) < 7 {
+		return false
 	}
+	return strings.HasPrefix(target, Commit) || strings.HasPrefix(Commit, target)
+}
 
-	// Make the temporary file executable
-	if err := os.Chmod(tmpFile, 0755); err != nil {
-		helpers.PrintError("Failed to make downloaded binary executable", err)
-		err := os.Remove(tmpFile) // Clean up
-		if err != nil {
-			helpers.PrintError("Failed to remove temporary file", err)
-			return err
+// findRelease returns the release of kettle tagged version, with or
+// without a leading v.
+func findRelease(ctx context.Context, version string) (*github.RepositoryRelease, error) {
+	tags := []string{version}
+	if trimmed := strings.TrimPrefix(version, "v"); trimmed == version {
+		tags = append(tags, "v"+version)
+	} else {
+		tags = append(tags, trimmed)
+	}
+	var err error
+	for _, tag := range tags {
+		var release *github.RepositoryRelease
+		if release, err = helpers.GithubGetReleaseByTag(ctx, kettleOwner, kettleRepo, tag); err == nil {
+			return release, nil
 		}
-		return err
 	}
+	return nil, fmt.Errorf("no kettle release %s: %w", version, err)
+}
 
-	helpers.PrintInfo("Replacing current binary...")
-
-	// Use atomic replacement to handle the case where the binary is in use
-	if err := atomicReplace(currentExe, tmpFile); err != nil {
-		helpers.PrintError("Failed to replace current binary", err)
-		err := os.Remove(tmpFile) // Clean up
+func updateKettle(ctx context.Context, channel, version string) error {
+	var release *github.RepositoryRelease
+	if version != "" {
+		helpers.PrintInfo(fmt.Sprintf("Looking up kettle %s...", version))
+		r, err := findRelease(ctx, version)
 		if err != nil {
-			helpers.PrintError("Failed to remove temporary file", err)
+			helpers.PrintError("Failed to fetch release information", err)
 			return err
 		}
-		return err
-	}
-
-	helpers.PrintSuccess(fmt.Sprintf("Kettle has been successfully updated from v%s to v%s!", currentVersion, latestVersion))
-	helpers.PrintInfo("You may need to restart your terminal session for changes to take effect.")
-
-	return nil
-}
-
-// getLatestRelease fetches the latest release information from GitHub API
-func getLatestRelease() (*GitHubRelease, error) {
-	resp, err := http.Get(githubAPIReleasesURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch release information: %w", err)
-	}
-	defer func() {
-		err := resp.Body.Close()
+		release = r
+	} else {
+		helpers.PrintInfo(fmt.Sprintf("Checking for the latest kettle version on the %s channel...", channel))
+		releases, err := helpers.GithubListReleases(ctx, kettleOwner, kettleRepo)
 		if err != nil {
-			helpers.PrintError("Failed to close response body", err)
+			helpers.PrintError("Failed to fetch latest release information", err)
+			return err
+		}
+		if release = channelRelease(releases, channel); release == nil {
+			return fmt.Errorf("no kettle release on the %s channel", channel)
 		}
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned status: %s", resp.Status)
-	}
-
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return nil, fmt.Errorf("failed to parse release information: %w", err)
-	}
-
-	return &release, nil
-}
-
-// compareVersions compares two semantic version strings
-// Returns: -1 if v1 < v2, 0 if v1 == v2, 1 if v1 > v2
-func compareVersions(v1, v2 string) int {
-	// Handle dev versions
-	if v1 == "dev" && v2 != "dev" {
-		return -1 // dev is always older than any released version
-	}
-	if v1 != "dev" && v2 == "dev" {
-		return 1 // any released version is newer than dev
-	}
-	if v1 == "dev" && v2 == "dev" {
-		return 0 // dev == dev
 	}
 
-	// Parse version components
-	parts1 := parseVersion(v1)
-	parts2 := parseVersion(v2)
+	targetVersion := strings.TrimPrefix(release.GetTagName(), "v")
+	currentVersion := strings.TrimPrefix(Version, "v")
 
-	// Compare each component
-	for i := 0; i < 3; i++ {
-		if parts1[i] < parts2[i] {
-			return -1
+	helpers.PrintInfo(fmt.Sprintf("Current version: v%s", currentVersion))
+	helpers.PrintInfo(fmt.Sprintf("Target version: v%s", targetVersion))
+	if version == "" && channel == config.ChannelNightly {
+		// Nightly tags do not order as versions, so the newest build is
+		// whichever was published last; only its identity matters.
+		if isRunningBuild(release) {
+			helpers.PrintSuccess("You are already running the latest version!")
+			return nil
+		}
+		if !helpers.PromptYesNo(fmt.Sprintf("Update kettle from v%s to v%s?", currentVersion, targetVersion), true) {
+			return helpers.ErrDeclined
 		}
-		if parts1[i] > parts2[i] {
-			return 1
+	} else {
+		cmp := helpers.CompareVersions(currentVersion, targetVersion)
+		switch {
+		case cmp == 0 || (cmp > 0 && version == ""):
+			helpers.PrintSuccess("You are already running the latest version!")
+			return nil
+		case cmp > 0 && !helpers.PromptYesNo(fmt.Sprintf("Downgrade kettle from v%s to v%s?", currentVersion, targetVersion), false):
+			return helpers.ErrDeclined
+		case cmp < 0 && !helpers.PromptYesNo(fmt.Sprintf("Update kettle from v%s to v%s?", currentVersion, targetVersion), true):
+			return helpers.ErrDeclined
 		}
 	}
 
-	return 0
-}
-
-// parseVersion parses a version string into [major, minor, patch] integers
-func parseVersion(version string) [3]int {
-	parts := [3]int{0, 0, 0}
-	versionParts := strings.Split(version, ".")
-
-	for i, part := range versionParts {
-		if i >= 3 {
-			break
-		}
-		// Simple integer parsing - ignoring errors and defaulting to 0
-		if val := 0; len(part) > 0 {
-			for _, char := range part {
-				if char >= '0' && char <= '9' {
-					val = val*10 + int(char-'0')
-				} else {
-					break // Stop at first non-digit
-				}
-			}
-			parts[i] = val
-		}
+	helpers.PrintInfo(fmt.Sprintf("Downloading kettle v%s...", targetVersion))
+	if err := installRelease(ctx, release, targetVersion); err != nil {
+		helpers.PrintError("Failed to update kettle", err)
+		return err
 	}
 
-	return parts
+	helpers.PrintSuccess(fmt.Sprintf("Kettle has been successfully updated from v%s to v%s!", currentVersion, targetVersion))
+	helpers.PrintInfo("The previous version was kept; run kettle update --rollback to go back to it.")
+	helpers.PrintInfo("You may need to restart your terminal session for changes to take effect.")
+
+	return nil
 }
 
 // atomicReplace performs an atomic replacement of the target file with the source file
@@ -206,7 +227,7 @@ func atomicReplace(target, source string) error {
 // unixReplace performs atomic replacement on Unix-like systems
 func unixReplace(target, source string) error {
 	// On Unix systems, rename is atomic and can replace a file even if it's currently executing
-	if err := os.Rename(source, target); err != nil {
+	if err := helpers.Rename(source, target); err != nil {
 		return fmt.Errorf("failed to replace binary: %w", err)
 	}
 	return nil
@@ -224,13 +245,12 @@ move "%s" "%s"
 del "%%~f0"
 `, source, target)
 
-	if err := os.WriteFile(batchScript, []byte(batchContent), 0755); err != nil {
+	if err := helpers.WriteFile(batchScript, []byte(batchContent), 0755); err != nil {
 		return fmt.Errorf("failed to create update script: %w", err)
 	}
 
 	// Start the batch script in the background
-	cmd := exec.Command("cmd", "/C", "start", "/B", batchScript)
-	if err := cmd.Start(); err != nil {
+	if err := helpers.StartDetached("cmd", "/C", "start", "/B", batchScript); err != nil {
 		helpers.PrintError("Failed to start update script", err)
 		return err
 	}
@@ -241,4 +261,12 @@ del "%%~f0"
 
 func init() {
 	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().StringVar(&updateChannel, "channel", "", "Switch to the stable, beta or nightly channel and remember it")
+	updateCmd.Flags().StringVar(&updateVersion, "version", "", "Install this exact version, even if it is older")
+	updateCmd.Flags().BoolVar(&updateRollback, "rollback", false, "Put back the version the last update replaced")
+	updateCmd.Flags().BoolVar(&skipVerify, "no-verify", false, "Install even if the release publishes no checksum")
+	updateCmd.MarkFlagsMutuallyExclusive("rollback", "channel")
+	updateCmd.MarkFlagsMutuallyExclusive("rollback", "version")
+	_ = updateCmd.RegisterFlagCompletionFunc("channel", cobra.FixedCompletions(updateChannels, cobra.ShellCompDirectiveNoFileComp))
 }
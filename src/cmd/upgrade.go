@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	"github.com/kettleofketchup/kettle/src/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var upgradeAll bool
+
+// installTool installs tool with its registered installer.
+func installTool(ctx context.Context, tool registry.Tool) error {
+	if tool.Installer == nil {
+		return fmt.Errorf("%s has no installer", tool.Name)
+	}
+	return tool.Installer(ctx)
+}
+
+// installToolVersion installs version, a release of tool, through its
+// InstallVersion, or with its installer when it cannot install a given
+// version.
+func installToolVersion(ctx context.Context, tool registry.Tool, version string) error {
+	if tool.InstallVersion != nil && version != "" {
+		return tool.InstallVersion(ctx, version)
+	}
+	return installTool(ctx, tool)
+}
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [tool...]",
+	Short: "Upgrade installed tools to their latest release",
+	Long: `Upgrade the named tools, or every outdated tool with --all, to their latest
+release. Tools pinned with tools.pinned in the config file are skipped.
+
+You are asked once to confirm the whole batch.`,
+	ValidArgsFunction: toolNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if upgradeAll == (len(args) > 0) {
+			return errors.New("name the tools to upgrade or pass --all")
+		}
+
+		tools := registry.All()
+		if !upgradeAll {
+			tools = nil
+			for _, name := range args {
+				tool, err := lookupTool(name)
+				if err != nil {
+					return err
+				}
+				tools = append(tools, tool)
+			}
+		}
+
+		cfg := config.Current()
+		checked := map[string]bool{}
+		var todo []Outdated
+		for _, o := range checkOutdated(cmd.Context(), tools) {
+			checked[o.Tool] = true
+			switch {
+			case o.Status == OutdatedPinned:
+				helpers.PrintInfo(fmt.Sprintf("Skipping %s: pinned by tools.pinned", o.Tool))
+			case o.Status == OutdatedUnknown && !upgradeAll:
+				helpers.PrintInfo(fmt.Sprintf("Skipping %s: could not tell whether it is outdated", o.Tool))
+			case o.Status == OutdatedUpToDate && !upgradeAll:
+				helpers.PrintInfo(fmt.Sprintf("%s %s is the latest version", o.Tool, o.Current))
+			case o.Status != OutdatedOutdated:
+			case !cfg.ToolEnabled(o.Tool):
+				helpers.PrintInfo(fmt.Sprintf("Skipping %s: disabled in the config file", o.Tool))
+			default:
+				todo = append(todo, o)
+			}
+		}
+		for _, name := range args {
+			if !checked[name] {
+				helpers.PrintInfo(fmt.Sprintf("%s is not installed; use its install command instead", name))
+			}
+		}
+
+		if len(todo) == 0 {
+			helpers.PrintSuccess("Nothing to upgrade.")
+			return nil
+		}
+		if !helpers.IsStructuredOutput() {
+			fmt.Println(outdatedTable(todo))
+		}
+		names := make([]string, len(todo))
+		for i, o := range todo {
+			names[i] = o.Tool
+		}
+		if !helpers.PromptYesNo(fmt.Sprintf("Upgrade %s?", strings.Join(names, ", ")), true) {
+			return helpers.ErrDeclined
+		}
+
+		helpers.SetReinstall(true)
+		defer helpers.SetReinstall(false)
+
+		var errs []error
+		for _, o := range todo {
+			if err := cmd.Context().Err(); err != nil {
+				return err
+			}
+			tool, _ := registry.Lookup(o.Tool)
+			helpers.PrintInfo(fmt.Sprintf("Upgrading %s from %s to %s...", o.Tool, o.Current, o.Latest))
+			err := installToolVersion(cmd.Context(), tool, o.Latest)
+			recordToolResult(tool, err)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to upgrade %s: %w", o.Tool, err))
+				continue
+			}
+			helpers.PrintSuccess(fmt.Sprintf("Upgraded %s to %s", o.Tool, o.Latest))
+		}
+		return errors.Join(errs...)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().BoolVar(&upgradeAll, "all", false, "Upgrade every outdated tool")
+}
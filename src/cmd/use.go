@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/spf13/cobra"
+)
+
+// useCmd represents the use command
+var useCmd = &cobra.Command{
+	Use:   "use <tool>[@<version>]",
+	Short: "Switch a tool to a specific version, or list its kept versions",
+	Long: `Make version the active version of a tool. Kept versions, and for Go and
+Node.js the versions installed side by side, are switched to immediately;
+tools that support it download other versions first. A prefix such as
+1.22 picks the newest kept or installed match.
+
+Without @<version>, the kept versions are listed and the active one is marked.`,
+	Example: `  kettle use golangci-lint@1.59.1
+  kettle use golangci-lint`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: toolNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, version, _ := strings.Cut(args[0], "@")
+		version = strings.TrimPrefix(version, "v")
+		tool, err := lookupTool(name)
+		if err != nil {
+			return err
+		}
+		versions, active, err := toolVersions(tool)
+		if err != nil {
+			return err
+		}
+
+		if version == "" {
+			if helpers.IsStructuredOutput() {
+				helpers.SetReportData(Switch{Tool: tool.Name, To: active, Versions: versions})
+				return nil
+			}
+			if len(versions) == 0 {
+				helpers.PrintInfo(fmt.Sprintf("No versions of %s are kept", tool.Name))
+				return nil
+			}
+			for _, v := range versions {
+				mark := "  "
+				if v == active {
+					mark = "* "
+				}
+				fmt.Println(mark + v)
+			}
+			return nil
+		}
+
+		if match := helpers.NewestMatch(versions, version); match != "" {
+			version = match
+		}
+		if version == active {
+			helpers.PrintInfo(fmt.Sprintf("%s %s is already active", tool.Name, version))
+			return nil
+		}
+		if !slices.Contains(versions, version) {
+			if tool.InstallVersion == nil {
+				return fmt.Errorf("%s %s is not kept (kept: %s)", tool.Name, version, strings.Join(versions, ", "))
+			}
+			// Installing activates the new version and records it.
+			if err := tool.InstallVersion(cmd.Context(), version); err != nil {
+				return err
+			}
+			recordToolResult(tool, nil)
+			return nil
+		}
+		return switchVersion(cmd.Context(), tool, active, version)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(useCmd)
+}
@@ -42,6 +42,7 @@ var versionCmd = &cobra.Command{
 	Short: "Show the version of kettle",
 	Long:  `Display the current version, commit hash, and build date of kettle.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		helpers.SetReportData(map[string]string{"version": Version, "commit": Commit, "build_date": Date})
 		helpers.PrintInfo(fmt.Sprintf("kettle %s", GetVersion()))
 	},
 }
@@ -0,0 +1,331 @@
+// Package config loads kettle's user configuration from
+// ~/.config/kettle/config.yaml. Values can be overridden by KETTLE_*
+// environment variables, which in turn are overridden by flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kettleofketchup/kettle/src/internal/paths"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FileName is the name of the config file inside kettle's config directory.
+	FileName = "config.yaml"
+	// PathEnv overrides the location of the config file.
+	PathEnv = "KETTLE_CONFIG"
+)
+
+// Update channels.
+const (
+	ChannelStable  = "stable"
+	ChannelBeta    = "beta"
+	ChannelNightly = "nightly"
+)
+
+// UpdateCheckOff turns off the update notice.
+const UpdateCheckOff = "off"
+
+// Prompt defaults.
+const (
+	PromptAsk = "ask"
+	PromptYes = "yes"
+	PromptNo  = "no"
+)
+
+// Config is kettle's user configuration.
+type Config struct {
+	// InstallPrefix is where binaries are installed, under <prefix>/bin.
+	InstallPrefix string `json:"install_prefix,omitempty" yaml:"install_prefix,omitempty"`
+	// Shells lists the shells kettle generates completions and profiles for.
+	Shells []string `json:"shells,omitempty" yaml:"shells,omitempty"`
+	GitHub GitHub   `json:"github,omitempty" yaml:"github,omitempty"`
+	// Proxy is used for HTTP and HTTPS downloads unless HTTPS_PROXY is set.
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// UpdateChannel picks the releases `kettle update` installs.
+	UpdateChannel string      `json:"update_channel,omitempty" yaml:"update_channel,omitempty"`
+	UpdateCheck   UpdateCheck `json:"update_check,omitempty" yaml:"update_check,omitempty"`
+	Prompts       Prompts     `json:"prompts,omitempty" yaml:"prompts,omitempty"`
+	Tools         Tools       `json:"tools,omitempty" yaml:"tools,omitempty"`
+	// Sets defines tool sets for `kettle sets`, keyed by name. A set with
+	// the name of a built-in set replaces it.
+	Sets map[string]Set `json:"sets,omitempty" yaml:"sets,omitempty"`
+}
+
+// Set is a named bundle of tools.
+type Set struct {
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Tools       []string `json:"tools" yaml:"tools"`
+}
+
+// GitHub configures where the token for GitHub API requests comes from.
+type GitHub struct {
+	// TokenEnv names the environment variable holding the token.
+	TokenEnv string `json:"token_env,omitempty" yaml:"token_env,omitempty"`
+	// TokenCommand prints the token, e.g. "gh auth token". It wins over TokenEnv.
+	TokenCommand string `json:"token_command,omitempty" yaml:"token_command,omitempty"`
+}
+
+// UpdateCheck configures the notice printed after a command when kettle
+// or a tool it installed has a newer release.
+type UpdateCheck struct {
+	// Interval is how long a check is reused, such as 24h, or off.
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+// Prompts configures how yes/no questions are answered.
+type Prompts struct {
+	// Default is ask, yes or no.
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// Tools restricts which tools kettle will install.
+type Tools struct {
+	// Enabled, when not empty, lists the only tools that may be installed.
+	Enabled []string `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// Disabled lists tools that may not be installed.
+	Disabled []string `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	// Pinned lists tools `kettle upgrade` leaves alone, as "name" to keep
+	// whatever is installed or "name@version" to only take releases within
+	// version.
+	Pinned []string `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() Config {
+	return Config{
+		GitHub:        GitHub{TokenEnv: "GITHUB_TOKEN"},
+		UpdateChannel: ChannelStable,
+		UpdateCheck:   UpdateCheck{Interval: "24h"},
+		Prompts:       Prompts{Default: PromptAsk},
+	}
+}
+
+var (
+	mu      sync.RWMutex
+	current = Default()
+)
+
+// Current returns the configuration of the running command.
+func Current() Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// SetCurrent sets the configuration of the running command.
+func SetCurrent(c Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+}
+
+// ToolEnabled reports whether the config allows installing the named tool.
+func (c Config) ToolEnabled(name string) bool {
+	if slices.Contains(c.Tools.Disabled, name) {
+		return false
+	}
+	return len(c.Tools.Enabled) == 0 || slices.Contains(c.Tools.Enabled, name)
+}
+
+// Pin reports whether the named tool is pinned, and the version it is
+// pinned to, which is "" when it is held at whatever is installed.
+func (c Config) Pin(name string) (version string, pinned bool) {
+	for _, pin := range c.Tools.Pinned {
+		tool, version, _ := strings.Cut(pin, "@")
+		if tool == name {
+			return version, true
+		}
+	}
+	return "", false
+}
+
+// UpdateCheckInterval returns how long an update check is reused, and
+// false when the update notice is off.
+func (c Config) UpdateCheckInterval() (time.Duration, bool) {
+	if c.UpdateCheck.Interval == UpdateCheckOff {
+		return 0, false
+	}
+	d, err := time.ParseDuration(c.UpdateCheck.Interval)
+	return d, err == nil && d > 0
+}
+
+// Error is a configuration problem, pointing at the line that caused it
+// when there is one.
+type Error struct {
+	Source string
+	Line   int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.Source, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Source, e.Msg)
+}
+
+// DefaultPath returns the config file location: $KETTLE_CONFIG, or
+// config.yaml in kettle's config directory.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path, nil
+	}
+	dir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the config file at path on top of the defaults. A missing
+// file is not an error.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+	return Parse(data, path)
+}
+
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Parse decodes and validates config file content. source names the file
+// in errors.
+func Parse(data []byte, source string) (Config, error) {
+	cfg := Default()
+	if len(bytes.TrimSpace(data)) == 0 {
+		return cfg, nil
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, decodeError(source, err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return Config{}, decodeError(source, err)
+	}
+	if err := cfg.validate(source, func(key string) int { return keyLine(&root, key) }); err != nil {
+		return Config{}, err
+	}
+	cfg.InstallPrefix = expandHome(cfg.InstallPrefix)
+	return cfg, nil
+}
+
+// decodeError turns a yaml error into Errors with line numbers.
+func decodeError(source string, err error) error {
+	var typeErr *yaml.TypeError
+	msgs := []string{err.Error()}
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+	var errs []error
+	for _, msg := range msgs {
+		e := &Error{Source: source, Msg: msg}
+		if m := yamlLine.FindStringSubmatch(msg); m != nil {
+			e.Line, _ = strconv.Atoi(m[1])
+			e.Msg = m[2]
+		}
+		errs = append(errs, e)
+	}
+	return errors.Join(errs...)
+}
+
+// keyLine returns the line of a dotted key in a parsed document, or 0.
+func keyLine(root *yaml.Node, key string) int {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := 0
+	for _, part := range strings.Split(key, ".") {
+		if node.Kind != yaml.MappingNode {
+			return line
+		}
+		found := false
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == part {
+				line = node.Content[i].Line
+				node = node.Content[i+1]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return line
+		}
+	}
+	return line
+}
+
+// validate checks values the YAML schema cannot. lineOf finds the line of
+// a key in the source, or 0 when there is none.
+func (c Config) validate(source string, lineOf func(key string) int) error {
+	var errs []error
+	bad := func(key, format string, args ...any) {
+		errs = append(errs, &Error{Source: source, Line: lineOf(key), Msg: key + ": " + fmt.Sprintf(format, args...)})
+	}
+
+	if c.InstallPrefix != "" && !filepath.IsAbs(expandHome(c.InstallPrefix)) {
+		bad("install_prefix", "must be an absolute path, got %q", c.InstallPrefix)
+	}
+	for _, shell := range c.Shells {
+		if !slices.Contains([]string{"bash", "zsh", "fish"}, shell) {
+			bad("shells", "unsupported shell %q (want bash, zsh or fish)", shell)
+		}
+	}
+	if c.Proxy != "" {
+		if u, err := url.Parse(c.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			bad("proxy", "must be a URL like http://proxy:3128, got %q", c.Proxy)
+		}
+	}
+	for _, pin := range c.Tools.Pinned {
+		if tool, version, found := strings.Cut(pin, "@"); tool == "" || (found && version == "") {
+			bad("tools.pinned", "must be name or name@version, got %q", pin)
+		}
+	}
+	for name, set := range c.Sets {
+		if len(set.Tools) == 0 {
+			bad("sets."+name, "must list at least one tool")
+		}
+	}
+	if !slices.Contains([]string{ChannelStable, ChannelBeta, ChannelNightly}, c.UpdateChannel) {
+		bad("update_channel", "must be stable, beta or nightly, got %q", c.UpdateChannel)
+	}
+	if c.UpdateCheck.Interval != UpdateCheckOff {
+		if d, err := time.ParseDuration(c.UpdateCheck.Interval); err != nil || d <= 0 {
+			bad("update_check.interval", "must be a duration such as 24h, or off, got %q", c.UpdateCheck.Interval)
+		}
+	}
+	if !slices.Contains([]string{PromptAsk, PromptYes, PromptNo}, c.Prompts.Default) {
+		bad("prompts.default", "must be ask, yes or no, got %q", c.Prompts.Default)
+	}
+	return errors.Join(errs...)
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
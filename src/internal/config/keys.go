@@ -0,0 +1,210 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// field is a settable config value addressed by a dotted key such as
+// "github.token_env".
+type field struct {
+	key   string
+	index []int
+	list  bool
+}
+
+// fields lists every config key in declaration order. Maps such as sets
+// are only edited in the file, so they have no key.
+func fields() []field {
+	var out []field
+	var walk func(t reflect.Type, prefix string, index []int)
+	walk = func(t reflect.Type, prefix string, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			idx := append(append([]int(nil), index...), i)
+			switch f.Type.Kind() {
+			case reflect.Struct:
+				walk(f.Type, prefix+name+".", idx)
+			case reflect.Map:
+			case reflect.Slice:
+				out = append(out, field{key: prefix + name, index: idx, list: true})
+			default:
+				out = append(out, field{key: prefix + name, index: idx})
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "", nil)
+	return out
+}
+
+func lookupField(key string) (field, error) {
+	for _, f := range fields() {
+		if f.key == key {
+			return f, nil
+		}
+	}
+	return field{}, fmt.Errorf("unknown config key %q (see `kettle config show`)", key)
+}
+
+// Keys returns every config key.
+func Keys() []string {
+	var keys []string
+	for _, f := range fields() {
+		keys = append(keys, f.key)
+	}
+	return keys
+}
+
+// EnvName returns the environment variable that overrides key, e.g.
+// KETTLE_GITHUB_TOKEN_ENV for github.token_env.
+func EnvName(key string) string {
+	return "KETTLE_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Get returns the value of key. Lists are comma separated.
+func (c Config) Get(key string) (string, error) {
+	f, err := lookupField(key)
+	if err != nil {
+		return "", err
+	}
+	v := reflect.ValueOf(c).FieldByIndex(f.index)
+	if f.list {
+		return strings.Join(v.Interface().([]string), ","), nil
+	}
+	return v.String(), nil
+}
+
+func (c *Config) set(f field, value string) {
+	v := reflect.ValueOf(c).Elem().FieldByIndex(f.index)
+	if f.list {
+		v.Set(reflect.ValueOf(splitList(value)))
+		return
+	}
+	v.SetString(value)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ApplyEnv overrides c with any KETTLE_* variables that are set.
+func (c *Config) ApplyEnv() error {
+	var set []string
+	for _, f := range fields() {
+		if value, ok := os.LookupEnv(EnvName(f.key)); ok {
+			c.set(f, value)
+			set = append(set, f.key)
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	c.InstallPrefix = expandHome(c.InstallPrefix)
+	return c.validate("environment", func(key string) int { return 0 })
+}
+
+// SetInFile sets key to value in the config file content data and returns
+// the new content. Comments and the order of other keys are kept. An empty
+// value removes the key. The result is validated before it is returned.
+func SetInFile(data []byte, key, value string) ([]byte, error) {
+	f, err := lookupField(key)
+	if err != nil {
+		return nil, err
+	}
+	check := Default()
+	check.set(f, value)
+	if value != "" {
+		if err := check.validate("value", func(string) int { return 0 }); err != nil {
+			return nil, err
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, decodeError(FileName, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, &Error{Source: FileName, Line: root.Line, Msg: "top level must be a mapping"}
+	}
+
+	parts := strings.Split(key, ".")
+	node := root
+	for _, part := range parts[:len(parts)-1] {
+		child := mappingValue(node, part)
+		if child == nil {
+			if value == "" {
+				return data, nil
+			}
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, child)
+		}
+		if child.Kind != yaml.MappingNode {
+			return nil, &Error{Source: FileName, Line: child.Line, Msg: part + ": must be a mapping"}
+		}
+		node = child
+	}
+
+	last := parts[len(parts)-1]
+	newValue := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if f.list {
+		newValue = &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range splitList(value) {
+			newValue.Content = append(newValue.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: item})
+		}
+	}
+	replaced := false
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != last {
+			continue
+		}
+		if value == "" {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+		} else {
+			node.Content[i+1] = newValue
+		}
+		replaced = true
+		break
+	}
+	if !replaced && value != "" {
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: last}, newValue)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	if _, err := Parse(buf.Bytes(), FileName); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package paths resolves where kettle keeps its files, following the XDG
+// Base Directory specification. Relative XDG_* values are ignored, as the
+// specification requires.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Location is a resolved directory and where it came from.
+type Location struct {
+	Name string `json:"name" yaml:"name"`
+	Path string `json:"path" yaml:"path"`
+	// Env is the variable that set Path, or "" for the default.
+	Env string `json:"env,omitempty" yaml:"env,omitempty"`
+}
+
+func home() (string, error) {
+	h, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home directory: %w", err)
+	}
+	return h, nil
+}
+
+// xdg returns $env, or fallback under the home directory when it is unset
+// or relative, along with the variable that was used.
+func xdg(env string, fallback ...string) (string, string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir, env, nil
+	}
+	h, err := home()
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(append([]string{h}, fallback...)...), "", nil
+}
+
+func kettleDir(env string, fallback ...string) (string, error) {
+	dir, _, err := xdg(env, fallback...)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kettle"), nil
+}
+
+// ConfigDir is $XDG_CONFIG_HOME/kettle, by default ~/.config/kettle.
+func ConfigDir() (string, error) { return kettleDir("XDG_CONFIG_HOME", ".config") }
+
+// CacheDir is $XDG_CACHE_HOME/kettle, by default ~/.cache/kettle.
+func CacheDir() (string, error) { return kettleDir("XDG_CACHE_HOME", ".cache") }
+
+// StateDir is $XDG_STATE_HOME/kettle, by default ~/.local/state/kettle.
+func StateDir() (string, error) { return kettleDir("XDG_STATE_HOME", ".local", "state") }
+
+// DataDir is $XDG_DATA_HOME/kettle, by default ~/.local/share/kettle.
+func DataDir() (string, error) { return kettleDir("XDG_DATA_HOME", ".local", "share") }
+
+// ConfigHome is $XDG_CONFIG_HOME itself, by default ~/.config, for other
+// programs' config files such as fish's config.fish.
+func ConfigHome() (string, error) {
+	dir, _, err := xdg("XDG_CONFIG_HOME", ".config")
+	return dir, err
+}
+
+// DataHome is $XDG_DATA_HOME itself, by default ~/.local/share, for files
+// other programs look for such as desktop entries.
+func DataHome() (string, error) {
+	dir, _, err := xdg("XDG_DATA_HOME", ".local", "share")
+	return dir, err
+}
+
+// BinDir is where user-level executables go: $XDG_BIN_HOME, by default
+// ~/.local/bin. It is shared with other programs, so there is no kettle
+// subdirectory.
+func BinDir() (string, error) {
+	dir, _, err := xdg("XDG_BIN_HOME", ".local", "bin")
+	return dir, err
+}
+
+// All returns every location kettle uses, for display.
+func All() ([]Location, error) {
+	dirs := []struct {
+		name, env string
+		fallback  []string
+		kettle    bool
+	}{
+		{"config", "XDG_CONFIG_HOME", []string{".config"}, true},
+		{"cache", "XDG_CACHE_HOME", []string{".cache"}, true},
+		{"state", "XDG_STATE_HOME", []string{".local", "state"}, true},
+		{"data", "XDG_DATA_HOME", []string{".local", "share"}, true},
+		{"bin", "XDG_BIN_HOME", []string{".local", "bin"}, false},
+	}
+	var locs []Location
+	for _, d := range dirs {
+		dir, env, err := xdg(d.env, d.fallback...)
+		if err != nil {
+			return nil, err
+		}
+		if d.kettle {
+			dir = filepath.Join(dir, "kettle")
+		}
+		locs = append(locs, Location{Name: d.name, Path: dir, Env: env})
+	}
+	return locs, nil
+}
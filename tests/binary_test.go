@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBinaryPlatform(t *testing.T) {
+	self, err := os.Executable()
+	require.NoError(t, err)
+
+	goos, goarchs, err := helpers.BinaryPlatform(self)
+	require.NoError(t, err)
+	assert.Equal(t, runtime.GOOS, goos)
+	assert.Equal(t, []string{runtime.GOARCH}, goarchs)
+
+	assert.NoError(t, helpers.CheckBinaryPlatform(self, runtime.GOOS, runtime.GOARCH))
+	err = helpers.CheckBinaryPlatform(self, "plan9", runtime.GOARCH)
+	assert.True(t, errors.Is(err, helpers.ErrVerification), "wrong OS: %v", err)
+
+	script := filepath.Join(t.TempDir(), "kettle")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho kettle\n"), 0755))
+	err = helpers.CheckBinaryPlatform(script, runtime.GOOS, runtime.GOARCH)
+	assert.True(t, errors.Is(err, helpers.ErrVerification), "not an executable: %v", err)
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	buildOnce sync.Once
+	kettleBin string
+	buildErr  error
+)
+
+// kettle runs a freshly built kettle with a scratch home and the given
+// environment, and returns its stdout, stderr and exit code.
+func kettle(t *testing.T, env []string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	buildOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "kettle-test-*")
+		if err != nil {
+			buildErr = err
+			return
+		}
+		kettleBin = filepath.Join(dir, "kettle")
+		out, err := exec.Command("go", "build", "-o", kettleBin, "..").CombinedOutput()
+		if err != nil {
+			buildErr = fmt.Errorf("go build: %w\n%s", err, out)
+		}
+	})
+	require.NoError(t, buildErr)
+
+	home := t.TempDir()
+	cmd := exec.Command(kettleBin, args...)
+	cmd.Env = append([]string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + home,
+		"XDG_CONFIG_HOME=" + filepath.Join(home, ".config"),
+		"XDG_STATE_HOME=" + filepath.Join(home, ".local", "state"),
+		"XDG_CACHE_HOME=" + filepath.Join(home, ".cache"),
+		"KETTLE_NONINTERACTIVE=1",
+		"CI=1",
+	}, env...)
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		code = exitErr.ExitCode()
+	} else {
+		require.NoError(t, err)
+	}
+	return out.String(), errOut.String(), code
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// effectiveConfig returns what `kettle config show` reports.
+func effectiveConfig(t *testing.T, env []string, args ...string) map[string]any {
+	t.Helper()
+	stdout, stderr, code := kettle(t, env, append([]string{"-o", "json", "config", "show"}, args...)...)
+	require.Equal(t, 0, code, stderr)
+	var report struct {
+		Data map[string]any `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &report), stdout)
+	return report.Data
+}
+
+func TestConfigFile(t *testing.T) {
+	path := writeConfig(t, "shells: [bash, zsh]\nprompts:\n  default: yes\ntools:\n  pinned: [go, node@20]\n")
+	cfg := effectiveConfig(t, nil, "--config", path)
+	assert.Equal(t, []any{"bash", "zsh"}, cfg["shells"])
+	assert.Equal(t, map[string]any{"default": "yes"}, cfg["prompts"])
+	assert.Equal(t, map[string]any{"pinned": []any{"go", "node@20"}}, cfg["tools"])
+	assert.Equal(t, "stable", cfg["update_channel"], "defaults fill in what the file leaves out")
+}
+
+func TestConfigErrorsHaveLineNumbers(t *testing.T) {
+	path := writeConfig(t, "shells: [bash]\nprompts:\n  default: maybe\nproxy: nope\n")
+	_, stderr, code := kettle(t, nil, "paths", "--config", path)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, path+`:3: prompts.default: must be ask, yes or no, got "maybe"`)
+	assert.Contains(t, stderr, path+`:4: proxy: must be a URL`)
+
+	path = writeConfig(t, "install_prefix: /opt/kettle\nbogus: 1\n")
+	_, stderr, code = kettle(t, nil, "paths", "--config", path)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, path+":2: field bogus not found")
+
+	_, stderr, code = kettle(t, []string{"KETTLE_UPDATE_CHANNEL=weekly"}, "paths")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `environment: update_channel: must be stable, beta or nightly, got "weekly"`)
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, "install_prefix: /opt/file\nupdate_channel: beta\n")
+	env := []string{"KETTLE_CONFIG=" + path}
+
+	cfg := effectiveConfig(t, env)
+	assert.Equal(t, "/opt/file", cfg["install_prefix"])
+
+	env = append(env, "KETTLE_INSTALL_PREFIX=/opt/env")
+	cfg = effectiveConfig(t, env)
+	assert.Equal(t, "/opt/env", cfg["install_prefix"], "KETTLE_* overrides the file")
+	assert.Equal(t, "beta", cfg["update_channel"], "keys without an override keep the file's value")
+
+	cfg = effectiveConfig(t, env, "--prefix", "/opt/flag")
+	assert.Equal(t, "/opt/flag", cfg["install_prefix"], "flags override KETTLE_*")
+}
@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kettleofketchup/kettle/src/cmd/languages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoModToolchain(t *testing.T) {
+	tests := []struct {
+		name  string
+		gomod string
+		want  string
+	}{
+		{"toolchain wins", "module x\n\ngo 1.22.0\n\ntoolchain go1.22.3\n", "1.22.3"},
+		{"go directive", "module x\n\ngo 1.21\n", "1.21"},
+		{"neither", "module x\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(tt.gomod), 0644))
+			sub := filepath.Join(dir, "cmd", "tool")
+			require.NoError(t, os.MkdirAll(sub, 0755))
+
+			got, path, err := languages.GoModToolchain(sub)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, filepath.Join(dir, "go.mod"), path)
+		})
+	}
+}
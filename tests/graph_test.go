@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"testing"
+
+	_ "github.com/kettleofketchup/kettle/src/cmd/languages"
+	"github.com/kettleofketchup/kettle/src/cmd/registry"
+	_ "github.com/kettleofketchup/kettle/src/cmd/tools/terminal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveOrdersDependenciesFirst(t *testing.T) {
+	registry.Register(registry.Tool{Name: "graph-a", Deps: []string{"graph-b", "graph-c"}})
+	registry.Register(registry.Tool{Name: "graph-b", Deps: []string{"graph-c"}})
+	registry.Register(registry.Tool{Name: "graph-c"})
+
+	tools, err := registry.Resolve([]string{"graph-a", "graph-c"})
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	assert.Equal(t, []string{"graph-c", "graph-b", "graph-a"}, names)
+}
+
+func TestResolveRejectsCycles(t *testing.T) {
+	registry.Register(registry.Tool{Name: "cycle-a", Deps: []string{"cycle-b"}})
+	registry.Register(registry.Tool{Name: "cycle-b", Deps: []string{"cycle-a"}})
+
+	_, err := registry.Resolve([]string{"cycle-a"})
+	assert.EqualError(t, err, "dependency cycle: cycle-a -> cycle-b -> cycle-a")
+
+	_, err = registry.Resolve([]string{"no-such-tool"})
+	assert.Error(t, err)
+}
+
+func TestToolsHaveInstallers(t *testing.T) {
+	for _, tool := range registry.All() {
+		if tool.Install == nil {
+			continue // registered by the tests above
+		}
+		assert.NotNil(t, tool.Installer, tool.Name)
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kettleofketchup/kettle/src/cmd/languages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeVersionFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+		from  string
+	}{
+		{"nvmrc", map[string]string{".nvmrc": "# pinned\nlts/iron\n"}, "lts/iron", ".nvmrc"},
+		{"node-version", map[string]string{".node-version": "v20.11.1\n"}, "v20.11.1", ".node-version"},
+		{"nvmrc wins", map[string]string{".nvmrc": "20", "package.json": `{"engines": {"node": ">=18"}}`}, "20", ".nvmrc"},
+		{"engines", map[string]string{"package.json": `{"name": "x", "engines": {"node": "^20.9.0"}}`}, "^20.9.0", "package.json"},
+		{"no engines", map[string]string{"package.json": `{"name": "x"}`}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+			}
+			sub := filepath.Join(dir, "src", "lib")
+			require.NoError(t, os.MkdirAll(sub, 0755))
+
+			got, path, err := languages.NodeVersionFile(sub)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			if tt.from != "" {
+				assert.Equal(t, filepath.Join(dir, tt.from), path)
+			}
+		})
+	}
+}
+
+func TestNodeRangeMatches(t *testing.T) {
+	tests := []struct {
+		rng     string
+		version string
+		want    bool
+	}{
+		{"20", "20.11.1", true},
+		{"20", "21.0.0", false},
+		{"20.11.1", "20.11.1", true},
+		{"20.x", "20.2.0", true},
+		{">=18", "22.11.0", true},
+		{">=18", "16.20.2", false},
+		{">= 18.17.0", "18.16.0", false},
+		{"^20.9.0", "20.11.1", true},
+		{"^20.9.0", "20.8.0", false},
+		{"^20.9.0", "21.0.0", false},
+		{"~20.11", "20.11.5", true},
+		{"~20.11", "20.12.0", false},
+		{">18 <21", "18.20.0", false},
+		{">18 <21", "20.1.0", true},
+		{"<=20", "20.18.0", true},
+		{"16 || >=20", "18.0.0", false},
+		{"16 || >=20", "16.1.0", true},
+		{"18 - 20", "20.5.0", true},
+		{"18 - 20", "21.0.0", false},
+		{"*", "22.0.0", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, languages.NodeRangeMatches(tt.version, tt.rng), "%s in %s", tt.version, tt.rng)
+	}
+}
@@ -35,3 +35,28 @@ func TestAssetSelection(t *testing.T) {
 	}
 
 }
+
+func TestRankAssetOtherArch(t *testing.T) {
+	other := map[string]string{"amd64": "aarch64", "arm64": "x86_64"}[runtime.GOARCH]
+	if other == "" {
+		t.Skip("no other architecture to compare with on " + runtime.GOARCH)
+	}
+	assert.Zero(t, helpers.RankAsset(fmt.Sprintf("kettle_%s_%s.tar.gz", runtime.GOOS, other)))
+	assert.Positive(t, helpers.RankAsset(fmt.Sprintf("kettle_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)))
+}
+
+func TestRankAssetSkipsChecksumsAndSignatures(t *testing.T) {
+	archive := fmt.Sprintf("kettle_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	assets := []string{
+		archive + ".sig",
+		archive + ".sha256",
+		archive + ".asc",
+		"checksums.txt",
+		"checksums.txt.sig",
+		archive,
+	}
+	for _, asset := range assets[:len(assets)-1] {
+		assert.Zero(t, helpers.RankAsset(asset), asset)
+	}
+	assert.Equal(t, archive, helpers.SelectBestAsset(assets))
+}
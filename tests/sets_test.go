@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/kettleofketchup/kettle/src/cmd/sets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceName(t *testing.T) {
+	cases := map[string]string{
+		"https://example.com/platform/team.yaml":               "team",
+		"git+https://example.com/dotfiles.git#kettle/":         "kettle",
+		"git+https://example.com/dotfiles.git#kettle/dev.yaml": "dev",
+		"git+https://example.com/dotfiles.git":                 "dotfiles",
+		"git+https://example.com/dotfiles.git#sets.yaml":       "dotfiles",
+		"https://example.com/":                                 "example.com",
+	}
+	for url, want := range cases {
+		assert.Equal(t, want, sets.SourceName(url), url)
+	}
+}
+
+const teamSets = "sets:\n  team:\n    tools: [go]\n"
+
+// serveSets serves teamSets as /team.yaml, and sig as /team.yaml.sig when
+// it is not empty.
+func serveSets(t *testing.T, sig string) string {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	mux := http.NewServeMux()
+	mux.HandleFunc("/team.yaml", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(teamSets)) })
+	if sig != "" {
+		mux.HandleFunc("/team.yaml.sig", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(sig)) })
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL + "/team.yaml"
+}
+
+func TestRefreshChecksSHA256(t *testing.T) {
+	url := serveSets(t, "")
+	sum := sha256.Sum256([]byte(teamSets))
+	digest := hex.EncodeToString(sum[:])
+
+	_, got, err := sets.Refresh(context.Background(), sets.Source{Name: "team", URL: url, SHA256: digest})
+	require.NoError(t, err)
+	assert.Equal(t, digest, got)
+
+	_, got, err = sets.Refresh(context.Background(), sets.Source{Name: "team", URL: url, SHA256: "00" + digest[2:]})
+	assert.True(t, errors.Is(err, helpers.ErrVerification), err)
+	assert.True(t, errors.Is(err, sets.ErrChanged), err)
+	assert.Equal(t, digest, got, "the new checksum, for sync to offer")
+}
+
+func TestRefreshChecksSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key := base64.StdEncoding.EncodeToString(pub)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(teamSets)))
+
+	url := serveSets(t, sig)
+	_, _, err = sets.Refresh(context.Background(), sets.Source{Name: "team", URL: url, PublicKey: key})
+	assert.NoError(t, err, "a valid signature")
+
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	url = serveSets(t, base64.StdEncoding.EncodeToString(ed25519.Sign(other, []byte(teamSets))))
+	_, _, err = sets.Refresh(context.Background(), sets.Source{Name: "team", URL: url, PublicKey: key})
+	assert.True(t, errors.Is(err, helpers.ErrVerification), err)
+	assert.ErrorContains(t, err, "bad signature")
+
+	url = serveSets(t, "")
+	_, _, err = sets.Refresh(context.Background(), sets.Source{Name: "team", URL: url, PublicKey: key})
+	assert.True(t, errors.Is(err, helpers.ErrVerification), err)
+	assert.ErrorContains(t, err, "has no signature")
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kettleofketchup/kettle/src/cmd/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeState(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "state.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadState(t *testing.T) {
+	doc, err := state.Load(writeState(t, `
+tools:
+  go: "1.23"
+files:
+  - path: /tmp/kettle-example
+    content: hello
+    mode: "0600"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "1.23", doc.Tools["go"])
+	assert.Equal(t, []string{"/tmp/kettle-example"}, doc.ManagedFiles())
+}
+
+func TestLoadStateRejectsInvalidFiles(t *testing.T) {
+	_, err := state.Load(writeState(t, "files:\n  - {path: /tmp/x, content: a, source: b}\n"))
+	assert.ErrorContains(t, err, "set content or source, not both")
+
+	_, err = state.Load(writeState(t, "files:\n  - {path: /tmp/x, mode: rw}\n"))
+	assert.ErrorContains(t, err, "not an octal permission")
+
+	_, err = state.Load(writeState(t, "tool:\n  go: latest\n"))
+	assert.ErrorContains(t, err, "field tool not found")
+}
+
+func TestApplyRecordsOnlyAppliedFiles(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good")
+	blocker := filepath.Join(dir, "blocker")
+	require.NoError(t, os.WriteFile(blocker, nil, 0644))
+	bad := filepath.Join(blocker, "bad")
+	path := writeState(t, "files:\n  - {path: "+good+", content: ok}\n  - {path: "+bad+", content: nope}\n")
+
+	stateHome := t.TempDir()
+	_, stderr, code := kettle(t, []string{"XDG_STATE_HOME=" + stateHome}, "apply", "--yes", "--file", path)
+	assert.NotEqual(t, 0, code, stderr)
+
+	data, err := os.ReadFile(filepath.Join(stateHome, "kettle", "managed.json"))
+	require.NoError(t, err)
+	var managed map[string]state.ManagedFile
+	require.NoError(t, json.Unmarshal(data, &managed))
+	assert.Contains(t, managed, good)
+	assert.NotContains(t, managed, bad, "a file that failed to apply is not managed")
+}
@@ -0,0 +1,27 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanupTempFilesRemovesDirectories(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "zoxide-0.9.4")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "unpacked"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "zoxide.tar.gz"), []byte("partial"), 0644))
+	file := filepath.Join(t.TempDir(), "kettle.download")
+	require.NoError(t, os.WriteFile(file, []byte("partial"), 0644))
+
+	helpers.TrackTempFile(dir)
+	helpers.TrackTempFile(file)
+	helpers.TrackTempFile(filepath.Join(t.TempDir(), "never-created"))
+	helpers.CleanupTempFiles()
+
+	assert.NoDirExists(t, dir)
+	assert.NoFileExists(t, file)
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/kettleofketchup/kettle/src/cmd/helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"dev", "0.0.1", -1},
+		{"dev", "dev", 0},
+		{"1.2.0-rc1", "1.2.0", -1},
+		{"1.2.0-rc.1", "1.2.0-rc.2", -1},
+		{"1.2.0-alpha", "1.2.0-alpha.1", -1},
+		{"1.2.0-alpha.1", "1.2.0-alpha.beta", -1},
+		{"1.2.0-beta.2", "1.2.0-beta.11", -1},
+		{"1.2.0-rc.1", "1.2.0-beta.11", 1},
+		{"1.2.0+build.5", "1.2.0", 0},
+		{"v1.2.0-3-gabc123", "v1.2.0", 1},
+		{"v1.2.0-3-gabc123", "v1.2.0-12-gdef456", -1},
+		{"v1.2.0-3-gabc123", "v1.2.1", -1},
+		{"1.23rc1", "1.23.0", -1},
+		{"abc1234", "0.0.1", -1},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, helpers.CompareVersions(c.a, c.b), "%s vs %s", c.a, c.b)
+	}
+}
+
+func TestVersionMatches(t *testing.T) {
+	assert.True(t, helpers.VersionMatches("1.23.4", "1.23"))
+	assert.True(t, helpers.VersionMatches("v20.11.1", "20"))
+	assert.True(t, helpers.VersionMatches("1.2.3", "v1.2.3"))
+	assert.False(t, helpers.VersionMatches("1.230.0", "1.23"))
+	assert.False(t, helpers.VersionMatches("1.24.0", "1.23"))
+	assert.False(t, helpers.VersionMatches("1.2.4", "1.2.3"))
+}
+
+func TestNewestMatch(t *testing.T) {
+	versions := []string{"1.21.9", "1.22.1", "1.22.3", "1.23.0"}
+	assert.Equal(t, "1.22.3", helpers.NewestMatch(versions, "1.22"))
+	assert.Equal(t, "1.22.1", helpers.NewestMatch(versions, "1.22.1"))
+	assert.Equal(t, "1.23.0", helpers.NewestMatch(versions, "1"))
+	assert.Equal(t, "", helpers.NewestMatch(versions, "1.24"))
+}
+
+func TestMatchRelease(t *testing.T) {
+	release := func(tag string, pre, draft bool) *github.RepositoryRelease {
+		return &github.RepositoryRelease{TagName: github.String(tag), Prerelease: github.Bool(pre), Draft: github.Bool(draft)}
+	}
+	releases := []*github.RepositoryRelease{
+		release("v1.60.0-rc.1", true, false),
+		release("v1.59.2", false, true),
+		release("v1.59.1", false, false),
+		release("v1.59.0", false, false),
+		release("v1.58.2", false, false),
+	}
+	tag := func(version string) string { return helpers.MatchRelease(releases, version).GetTagName() }
+
+	assert.Equal(t, "v1.59.1", tag("1.59"), "newest within the prefix, drafts skipped")
+	assert.Equal(t, "v1.59.0", tag("v1.59.0"))
+	assert.Equal(t, "v1.59.1", tag("1"), "prereleases need an exact version")
+	assert.Equal(t, "v1.60.0-rc.1", tag("1.60.0-rc.1"))
+	assert.Nil(t, helpers.MatchRelease(releases, "1.57"))
+}